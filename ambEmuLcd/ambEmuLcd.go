@@ -1,22 +1,32 @@
 package ambEmuLcd
 
-// #include "vrEmuLcd.h"
-import "C"
-
 import (
+	"encoding/json"
 	"fmt"
-	"github.com/tarm/serial"
 	"image"
+	"io"
 	"log"
-	"unsafe"
+	"sync"
+	"sync/atomic"
+	"time"
+	"uSDX/hd44780"
 )
 
 type Updated struct{}
 
+// PoweredOn and PoweredOff are sent when the controller board reports that the radio has been
+// switched on or off.
+type PoweredOn struct{}
+type PoweredOff struct{}
+
+// Settled is sent once the display has stopped changing, with what it shows.
 type Settled struct {
-	Line1Data []byte
-	Line2Data []byte
+	Lines     [][]byte // Every row of the display, as it shows it unshifted.
+	Line1Data []byte   // Lines[0]
+	Line2Data []byte   // Lines[1], or nil if the display has one row.
 	CursorPos image.Point
+	Time      time.Time // When the display was taken to have settled.
+	Bytes     int       // The bytes decoded since the previous Settled event.
 }
 
 // The microcontroller port pins used for each LCD signal
@@ -36,46 +46,119 @@ const (
 	DATA_REGISTER = 1
 )
 
-var state = WAITING_FOR_NIBBLE_1
+const eventBufferSize = 100
+
+const (
+	functionSetMask = 0b11100000
+	functionSet8Bit = 0b00010000
+)
+
+type AmbEmuLcd = *hd44780.Lcd
+
+// A Decoder decodes one stream of LCD nibbles from the controller board, driving its own LCD
+// emulator and sending Updated and Settled events on Events.
+type Decoder struct {
+	Events chan interface{}
 
-var rs, d7, d6, d5, d4 byte
+	// The display has settled once no bytes have arrived for QuietPeriod, or, if they never stop,
+	// once MaxSettleLatency has passed since the first byte that hasn't yet been settled.
+	QuietPeriod      time.Duration
+	MaxSettleLatency time.Duration
+	Clock            func() time.Time // Stands in for time.Now, e.g. to test the settle timing.
 
-type AmbEmuLcd = *C.VrEmuLcd
+	lcd      AmbEmuLcd
+	lcdMutex sync.Mutex // Guards lcd, which the GUI reads while the stream is decoded.
 
-var lcd AmbEmuLcd = C.vrEmuLcdNew(C.int(16), C.int(2), C.EmuLcdRomA02)
+	eightBit           bool // Whether the LCD has an 8 bit interface, as it does before the init sequence.
+	state              int
+	rs, d7, d6, d5, d4 byte
+	resyncs            int64 // Accessed atomically, so that Resyncs can be called while decoding.
 
-func cBytesToByteSlice(src *C.byte, start int, len int) []byte {
-	return (*(*[1 << 30]byte)(unsafe.Pointer(src)))[start : start+len : start+len]
+	unsettledBytes  int
+	firstUnsettled  time.Time
+	lastByteArrived time.Time
+	settledFrame    *frame // What the display showed when it last settled.
+
+	capture      *json.Encoder // Nil unless the serial data is being captured.
+	captureStart time.Time
 }
 
-func createSettledEvent() *Settled {
-	ddRam := C.vrEmuLcdGetDisplayRam(lcd)
-	// Following code assume 2 row, 16 col, unscrolled LCD.
-	line1 := cBytesToByteSlice(ddRam, 0x00, 16)
-	line2 := cBytesToByteSlice(ddRam, 0x40, 16)
+// NewDecoder returns a Decoder for a display of cols x rows characters, with the given ROM.
+// The radio is usually on already, so the decoder starts out expecting a 4 bit interface.
+func NewDecoder(cols, rows int, rom hd44780.Rom) *Decoder {
+	d := &Decoder{
+		Events:           make(chan interface{}, eventBufferSize),
+		QuietPeriod:      DefaultQuietPeriod,
+		MaxSettleLatency: DefaultMaxSettleLatency,
+		Clock:            time.Now,
+		lcd:              hd44780.New(cols, rows, rom),
+		state:            WAITING_FOR_NIBBLE_1,
+	}
+	d.settledFrame = d.captureFrame()
+	return d
+}
 
-	cCursor := C.vrEmuLcdGetCursorOffset(lcd)
-	goCursor := *(*int32)(unsafe.Pointer(&cCursor)) // is defined as int32_t in C code.
-	var cursorRow, cursorCol int
-	if 0 <= goCursor && goCursor < 0x40 {
-		cursorRow = 1
-		cursorCol = int(goCursor)
-	} else {
-		cursorRow = 2
-		cursorCol = int(goCursor) - 0x40
+// Feed decodes bytes from the controller board, which are taken to arrive now.
+func (d *Decoder) Feed(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	now := d.Clock()
+	if d.unsettledBytes == 0 {
+		d.firstUnsettled = now
+	}
+	d.unsettledBytes += len(data)
+	d.lastByteArrived = now
+
+	for _, b := range data {
+		d.interpretByteFromSerial(b)
 	}
-	return &Settled{
-		Line1Data: line1,
-		Line2Data: line2,
-		CursorPos: image.Point{X: cursorCol, Y: cursorRow},
+
+	if now.Sub(d.firstUnsettled) >= d.MaxSettleLatency {
+		d.settle(now)
 	}
 }
 
-// NOTE: The following ignores the fact that communication starts in 8bit data mode.
-// This works, but only by doing error-recovery from situations that are not actually errors.
-// A more sophisticated implementation would take the 8bit startup into account instead of
-// assuming only 4bit mode is used.
-func interpretByteFromSerial(nibbleByte byte, lcdEvents chan interface{}) {
+func (d *Decoder) createSettledEvent() *Settled {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+
+	ddRam := d.lcd.DisplayRam()
+	e := &Settled{CursorPos: d.cursorPos()}
+	for row := 0; row < d.lcd.Rows; row++ {
+		start := d.lcd.RowAddress(row)
+		e.Lines = append(e.Lines, append([]byte{}, ddRam[start:start+d.lcd.Cols]...))
+	}
+	e.Line1Data = e.Lines[0]
+	if len(e.Lines) > 1 {
+		e.Line2Data = e.Lines[1]
+	}
+	return e
+}
+
+// cursorPos returns the cursor's column, and its row counting from 1. The caller must hold lcdMutex.
+func (d *Decoder) cursorPos() image.Point {
+	goCursor := d.lcd.CursorOffset()
+	// The cursor is on the row that starts closest before it. E.g. on a 20x4 display, row 3
+	// starts at 0x14, straight after row 1.
+	cursorRow := 0
+	for row := 1; row < d.lcd.Rows; row++ {
+		start := d.lcd.RowAddress(row)
+		if start <= goCursor && start > d.lcd.RowAddress(cursorRow) {
+			cursorRow = row
+		}
+	}
+	return image.Point{X: goCursor - d.lcd.RowAddress(cursorRow), Y: cursorRow + 1}
+}
+
+// Each byte from the controller board is one write to the LCD: its RS bit and the 4 data lines.
+// The HD44780 powers up with an 8 bit interface, in which each write is a whole instruction.
+// The uSDX's init sequence switches it to 4 bits with function sets of 0x3, 0x3, 0x3, then 0x2,
+// after which each instruction or data byte takes two writes, high nibble first. Because 0x33
+// is itself a function set to 8 bits, the sequence also brings an LCD that's already in 4 bit
+// mode back into step, whichever nibble it was expecting. So does the decoder, which also
+// expects the init sequence after the controller board's power up and power down markers.
+func (d *Decoder) interpretByteFromSerial(nibbleByte byte) {
 
 	nibbleRS := (nibbleByte >> RS_BIT) & 1
 	nibbleD7 := (nibbleByte >> D7_BIT) & 1
@@ -83,20 +166,25 @@ func interpretByteFromSerial(nibbleByte byte, lcdEvents chan interface{}) {
 	nibbleD5 := (nibbleByte >> D5_BIT) & 1
 	nibbleD4 := (nibbleByte >> D4_BIT) & 1
 
-	switch state {
+	if d.eightBit {
+		d.interpretEightBitWrite(nibbleRS, nibbleD7<<3+nibbleD6<<2+nibbleD5<<1+nibbleD4<<0)
+		return
+	}
+
+	switch d.state {
 
 	case WAITING_FOR_NIBBLE_1:
 
 		// Second nibble should reference same register, so save 1st to compare later.
-		rs = nibbleRS
+		d.rs = nibbleRS
 
 		// Save the first nibble
-		d7 = nibbleD7
-		d6 = nibbleD6
-		d5 = nibbleD5
-		d4 = nibbleD4
+		d.d7 = nibbleD7
+		d.d6 = nibbleD6
+		d.d5 = nibbleD5
+		d.d4 = nibbleD4
 
-		state = WAITING_FOR_NIBBLE_2
+		d.state = WAITING_FOR_NIBBLE_2
 
 	case WAITING_FOR_NIBBLE_2:
 
@@ -105,80 +193,141 @@ func interpretByteFromSerial(nibbleByte byte, lcdEvents chan interface{}) {
 		d1 := nibbleD5
 		d0 := nibbleD4
 
-		fullByte := d7<<7 + d6<<6 + d5<<5 + d4<<4 + d3<<3 + d2<<2 + d1<<1 + d0<<0
+		fullByte := d.d7<<7 + d.d6<<6 + d.d5<<5 + d.d4<<4 + d3<<3 + d2<<2 + d1<<1 + d0<<0
 
 		// If RS values differ for two nibbles, it means that we're out of sync.
-		rsMismatchErr := rs != nibbleRS
+		rsMismatchErr := d.rs != nibbleRS
 
 		// If RS is zero then we have a command, but 00000000 is not a vaild command.
-		noSuchCommandErr := rs == 0 && fullByte == 0
+		noSuchCommandErr := d.rs == 0 && fullByte == 0
 
 		if !rsMismatchErr && !noSuchCommandErr {
 			// We are probably in sync so proceed normally.
-			interpretFullByte(fullByte, lcdEvents)
-			state = WAITING_FOR_NIBBLE_1
+			d.state = WAITING_FOR_NIBBLE_1
+			d.interpretFullByte(fullByte)
 		} else {
-			// We are definitely out of sync, so let's try to get back on track
-			//fmt.Printf("%01b %08b ERROR!\n", rs, fullByte)
-			rs = nibbleRS
-			d7 = nibbleD7
-			d6 = nibbleD6
-			d5 = nibbleD5
-			d4 = nibbleD4
-			state = WAITING_FOR_NIBBLE_2
+			// We are definitely out of sync, e.g. because a byte was lost, so let's try to get back on track
+			//fmt.Printf("%01b %08b ERROR!\n", d.rs, fullByte)
+			atomic.AddInt64(&d.resyncs, 1)
+			d.rs = nibbleRS
+			d.d7 = nibbleD7
+			d.d6 = nibbleD6
+			d.d5 = nibbleD5
+			d.d4 = nibbleD4
+			d.state = WAITING_FOR_NIBBLE_2
 		}
 	}
 }
 
-func interpretFullByte(b byte, lcdEvents chan interface{}) {
-	if rs == 0 && b == 255 { // Power UP signal is not a valid LCD command.
-		// lcdEvents <- PoweredOn{}
-	} else if rs == 0 && b == 254 { // Power DOWN signal is not a valid LCD command.
-		// lcdEvents <- PoweredOff{}
-		// C.vrEmuLcdSendCommand(lcd, 0b00000001) // Clear Display
-		// C.vrEmuLcdSendCommand(lcd, 0b00001100) // Cursor Off
+// interpretEightBitWrite interprets a write while the LCD has an 8 bit interface. Only D7-D4 are
+// wired up, so the low nibble of the instruction or data is read as zeros.
+func (d *Decoder) interpretEightBitWrite(rs byte, nibble byte) {
+
+	// The controller board's power markers are sent as two nibbles, even before the LCD is in 4 bit mode.
+	if d.state == WAITING_FOR_NIBBLE_2 {
+		d.state = WAITING_FOR_NIBBLE_1
+		if rs == 0 && (nibble == 0xF || nibble == 0xE) {
+			d.interpretFullByte(0xF0 | nibble)
+			return
+		}
+		d.interpretFullByte(0xF0) // It wasn't a marker, just a write of 0xF.
+	}
+	if rs == 0 && nibble == 0xF {
+		d.rs = 0
+		d.state = WAITING_FOR_NIBBLE_2
+		return
+	}
+
+	d.rs = rs
+	d.interpretFullByte(nibble << 4)
+}
+
+// flushEightBitWrite interprets a write that was held back in case it began a power marker.
+func (d *Decoder) flushEightBitWrite() {
+	if d.eightBit && d.state == WAITING_FOR_NIBBLE_2 {
+		d.state = WAITING_FOR_NIBBLE_1
+		d.interpretFullByte(0xF0)
+	}
+}
+
+func (d *Decoder) interpretFullByte(b byte) {
+	if d.rs == 0 && b == 255 { // Power UP signal is not a valid LCD command.
+		d.resetInterface()
+		d.Events <- PoweredOn{}
+	} else if d.rs == 0 && b == 254 { // Power DOWN signal is not a valid LCD command.
+		d.clearDisplay()
+		d.resetInterface()
+		d.Events <- PoweredOff{}
+	} else if d.rs == 0 && b&functionSetMask == hd44780.CmdFunction {
+		// Function set, which the emulator ignores, but which may change the interface width.
+		d.eightBit = b&functionSet8Bit != 0
+		d.state = WAITING_FOR_NIBBLE_1
 	} else {
-		sendFullByteToEmulator(b)
-		lcdEvents <- Updated{}
+		d.sendFullByteToEmulator(b)
+		d.Events <- Updated{}
 	}
 }
 
-func sendFullByteToEmulator(b byte) {
-	//fmt.Printf("%01b %08b\n", rs, b)
+// resetInterface puts the decoder in the LCD's power-on state, ready for the init sequence.
+func (d *Decoder) resetInterface() {
+	d.eightBit = true
+	d.state = WAITING_FOR_NIBBLE_1
+}
+
+// clearDisplay blanks the emulated display, as the radio's own goes blank when it's switched off.
+func (d *Decoder) clearDisplay() {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+	d.lcd.SendCommand(hd44780.CmdClear)
+	d.lcd.SendCommand(hd44780.CmdDisplay | hd44780.CmdDisplayOn) // Cursor off
+}
 
-	switch rs {
+// Resyncs returns how many times the decoder has had to recover from nibbles that made no sense.
+func (d *Decoder) Resyncs() int {
+	return int(atomic.LoadInt64(&d.resyncs))
+}
+
+func (d *Decoder) sendFullByteToEmulator(b byte) {
+	//fmt.Printf("%01b %08b\n", d.rs, b)
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+
+	switch d.rs {
 	case CMD_REGISTER:
-		C.vrEmuLcdSendCommand(lcd, C.byte(b))
+		d.lcd.SendCommand(b)
 	case DATA_REGISTER:
-		C.vrEmuLcdWriteByte(lcd, C.byte(b))
+		d.lcd.WriteData(b)
 	default:
 		panic("Bad RS value")
 	}
-	//C.vrEmuLcdPrintDisplayRam(lcd)
+	//fmt.Printf("%q\n", d.lcd.DisplayRam())
 }
 
-func NumPixels() (width int, height int) {
-	var cWidth C.int
-	var cHeight C.int
-	C.vrEmuLcdNumPixels(lcd, &cWidth, &cHeight)
-	return int(cWidth), int(cHeight)
+func (d *Decoder) NumPixels() (width int, height int) {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+	return d.lcd.NumPixels()
 }
 
-func PixelState(col int, row int) int {
-	return int(C.vrEmuLcdPixelState(lcd, C.int(col), C.int(row)))
+func (d *Decoder) PixelState(col int, row int) int {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+	return d.lcd.PixelState(col, row)
 }
 
-func UpdatePixels() {
-	C.vrEmuLcdUpdatePixels(lcd)
+func (d *Decoder) UpdatePixels() {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+	d.lcd.UpdatePixels()
 }
 
-func PrintPixels() {
-	pixelsWidth, pixelsHeight := NumPixels()
+func (d *Decoder) PrintPixels() {
+	pixelsWidth, pixelsHeight := d.NumPixels()
 	fmt.Printf("\n")
 	for row := 0; row < pixelsHeight; row++ {
 		fmt.Printf("   ")
 		for col := 0; col < pixelsWidth; col++ {
-			switch PixelState(col, row) {
+			switch d.PixelState(col, row) {
 			case 1:
 				fmt.Printf("█")
 				break
@@ -194,29 +343,39 @@ func PrintPixels() {
 	}
 }
 
-func ProcessSerialLcdData(uSdx *serial.Port, lcdEvents chan interface{}) {
-
-	buf := make([]byte, 128)
-	serialIsIdle := false
-
-	InitUsdxGlyphs(lcd)
-
-	for {
-		bytesRead, err := uSdx.Read(buf)
-		if err != nil && err.Error() != "EOF" {
-			log.Fatal(err)
-		}
-		if bytesRead == 0 {
-			if !serialIsIdle {
-				serialIsIdle = true
-				state = WAITING_FOR_NIBBLE_1
-				lcdEvents <- createSettledEvent()
+// ProcessSerialLcdData decodes the LCD data read from uSdx, sending a Settled event whenever the
+// data stops for the QuietPeriod. uSdx must be read with a timeout, so that captures record
+// where the controller board went idle.
+func (d *Decoder) ProcessSerialLcdData(uSdx io.Reader) {
+
+	d.lcdMutex.Lock()
+	InitUsdxGlyphs(d.lcd)
+	d.lcdMutex.Unlock()
+	d.settledFrame = d.captureFrame() // The glyphs are the decoder's own, not a change on the display.
+
+	reads := make(chan []byte)
+	go func() {
+		buf := make([]byte, 128)
+		serialIsIdle := false
+		for {
+			bytesRead, err := uSdx.Read(buf)
+			if err != nil && err.Error() != "EOF" {
+				log.Fatal(err)
 			}
-		} else {
-			for i := 0; i < bytesRead; i++ {
-				interpretByteFromSerial(buf[i], lcdEvents)
+			d.captureRead(buf[:bytesRead], serialIsIdle)
+			serialIsIdle = bytesRead == 0
+			if bytesRead > 0 {
+				reads <- append([]byte{}, buf[:bytesRead]...)
 			}
-			serialIsIdle = false
+		}
+	}()
+
+	for {
+		select {
+		case data := <-reads:
+			d.Feed(data)
+		case <-time.After(d.untilSettled()):
+			d.CheckSettled()
 		}
 	}
 }
@@ -0,0 +1,36 @@
+package ambEmuLcd
+
+import (
+	"image"
+	"testing"
+	"uSDX/hd44780"
+)
+
+func TestSettledFourRows(t *testing.T) {
+	d := NewDecoder(20, 4, hd44780.RomA02)
+	for _, write := range [][]byte{
+		nibbles(CMD_REGISTER, 0x80), text("row one"),
+		nibbles(CMD_REGISTER, 0xC0), text("row two"),
+		nibbles(CMD_REGISTER, 0x94), text("row three"),
+		nibbles(CMD_REGISTER, 0xD4), text("row four"),
+		nibbles(CMD_REGISTER, 0x96),
+	} {
+		d.Feed(write)
+	}
+	e := d.createSettledEvent()
+	want := []string{"row one", "row two", "row three", "row four"}
+	if len(e.Lines) != len(want) {
+		t.Fatalf("%d lines, want %d", len(e.Lines), len(want))
+	}
+	for i, line := range e.Lines {
+		if w := want[i] + "                    "[len(want[i]):]; string(line) != w {
+			t.Errorf("line %d is %q, want %q", i+1, line, w)
+		}
+	}
+	if string(e.Line1Data) != string(e.Lines[0]) || string(e.Line2Data) != string(e.Lines[1]) {
+		t.Errorf("Line1Data and Line2Data are %q and %q, not the first two lines", e.Line1Data, e.Line2Data)
+	}
+	if want := (image.Point{X: 2, Y: 3}); e.CursorPos != want {
+		t.Errorf("cursor at %v, want %v", e.CursorPos, want)
+	}
+}
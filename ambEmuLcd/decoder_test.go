@@ -0,0 +1,145 @@
+package ambEmuLcd
+
+import (
+	"strings"
+	"testing"
+	"uSDX/hd44780"
+)
+
+// Streams of writes from the controller board, as the uSDX would send them when it powers up or
+// resets, with the screens that the decoder should be left showing. They're synthesized, not
+// captured from a radio: the writes follow the uSDX firmware's LCD driver, i.e. the 8 bit function
+// sets, the switch to 4 bits, then function set, display on, clear and entry mode, and the screens
+// are laid out as the simulator draws them. Captures from a radio are replayed by
+// TestReplayCaptures.
+
+// A decoderFixture is a stream, the screen it should leave, how many resyncs it should take,
+// and the power events it should send, e.g. "PoweredOn PoweredOff".
+type decoderFixture struct {
+	name         string
+	stream       []byte
+	line1, line2 string
+	resyncs      int
+	power        string
+}
+
+const (
+	usdxSplash1 = "uSDX            "
+	usdxSplash2 = "QCX-SSB R1.02w  "
+	usdxMain1   = "                "
+	usdxMain2   = "\x06 7,074,00 USB  "
+	blankLine   = "                "
+)
+
+var decoderFixtures = []decoderFixture{
+	{"power up", stream(powerUpMarker(), usdxInit(), usdxSplash()),
+		usdxSplash1, usdxSplash2, 0, "PoweredOn"},
+
+	{"power up unannounced", stream(usdxInit(), usdxSplash()),
+		usdxSplash1, usdxSplash2, 0, ""},
+
+	{"power down", stream(powerUpMarker(), usdxInit(), usdxSplash(), usdxMainScreen(), powerDownMarker()),
+		blankLine, blankLine, 0, "PoweredOn PoweredOff"},
+
+	{"power cycle", stream(powerUpMarker(), usdxInit(), usdxMainScreen(), powerDownMarker(), powerUpMarker(), usdxInit(), usdxSplash()),
+		usdxSplash1, usdxSplash2, 0, "PoweredOn PoweredOff PoweredOn"},
+
+	{"reset mid command", stream(usdxInit(), usdxMainScreen(), nibbles(CMD_REGISTER, 0xC0)[:1], usdxInit(), usdxSplash()),
+		usdxSplash1, usdxSplash2, 0, ""},
+
+	{"reset mid data", stream(usdxInit(), usdxMainScreen(), nibbles(DATA_REGISTER, 'U')[:1], usdxInit(), usdxSplash()),
+		usdxSplash1, usdxSplash2, 1, ""},
+
+	{"main screen", stream(powerUpMarker(), usdxInit(), usdxSplash(), usdxMainScreen()),
+		usdxMain1, usdxMain2, 0, "PoweredOn"},
+}
+
+func stream(parts ...[]byte) []byte {
+	var s []byte
+	for _, p := range parts {
+		s = append(s, p...)
+	}
+	return s
+}
+
+// nibbles returns the two writes that send each byte over the 4 bit interface.
+func nibbles(rs byte, bytes ...byte) []byte {
+	var s []byte
+	for _, b := range bytes {
+		s = append(s, rs<<RS_BIT|b>>4, rs<<RS_BIT|b&0x0F)
+	}
+	return s
+}
+
+func text(s string) []byte {
+	return nibbles(DATA_REGISTER, []byte(s)...)
+}
+
+func powerUpMarker() []byte {
+	return nibbles(CMD_REGISTER, 0xFF)
+}
+
+func powerDownMarker() []byte {
+	return nibbles(CMD_REGISTER, 0xFE)
+}
+
+func usdxInit() []byte {
+	return stream(
+		[]byte{0x03, 0x03, 0x03, 0x02}, // Function sets over the 8 bit interface
+		nibbles(CMD_REGISTER,
+			hd44780.CmdFunction|hd44780.CmdFunction2Line,
+			hd44780.CmdDisplay|hd44780.CmdDisplayOn,
+			hd44780.CmdClear,
+			hd44780.CmdEntryMode|hd44780.CmdEntryModeIncrement,
+		),
+	)
+}
+
+func usdxSplash() []byte {
+	return stream(
+		nibbles(CMD_REGISTER, hd44780.CmdSetDdramAddr|0x00), text(usdxSplash1),
+		nibbles(CMD_REGISTER, hd44780.CmdSetDdramAddr|0x40), text(usdxSplash2),
+	)
+}
+
+func usdxMainScreen() []byte {
+	return stream(
+		nibbles(CMD_REGISTER, hd44780.CmdClear),
+		nibbles(CMD_REGISTER, hd44780.CmdSetDdramAddr|0x40), text(usdxMain2),
+	)
+}
+
+func TestDecoder(t *testing.T) {
+	for _, f := range decoderFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			d := NewDecoder(16, 2, hd44780.RomA02)
+			done := make(chan bool)
+			var power []string
+			go func() {
+				for e := range d.Events {
+					switch e.(type) {
+					case PoweredOn:
+						power = append(power, "PoweredOn")
+					case PoweredOff:
+						power = append(power, "PoweredOff")
+					}
+				}
+				done <- true
+			}()
+			d.Feed(f.stream)
+			close(d.Events)
+			<-done
+
+			screen := d.createSettledEvent()
+			if string(screen.Line1Data) != f.line1 || string(screen.Line2Data) != f.line2 {
+				t.Errorf("screen is %q %q, want %q %q", screen.Line1Data, screen.Line2Data, f.line1, f.line2)
+			}
+			if d.Resyncs() != f.resyncs {
+				t.Errorf("%d resyncs, want %d", d.Resyncs(), f.resyncs)
+			}
+			if strings.Join(power, " ") != f.power {
+				t.Errorf("power events %q, want %q", strings.Join(power, " "), f.power)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ambEmuLcd
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+)
+
+// The HD44780's CGRAM holds 8 custom characters, 0 to 7, which the uSDX uses for its glyphs.
+const customChars = 8
+
+// A Changed event follows a Settled event if the display differs from when it last settled.
+// It lists just what's different, so that a screen parser or redraw can skip the rest.
+type Changed struct {
+	Cells  []CellChange  // In order, row by row.
+	Cursor *CursorChange // Nil if the cursor is unchanged.
+	Glyphs []GlyphChange // Custom characters whose patterns were redefined.
+	Time   time.Time     // As in the Settled event.
+}
+
+// A CellChange is a character cell that shows a different character. Pos is the column, and the
+// row counting from 1, as in Settled.CursorPos.
+type CellChange struct {
+	Pos      image.Point
+	Was, Now byte
+}
+
+// CursorState is where the cursor is, and how it's shown.
+type CursorState struct {
+	Pos       image.Point
+	Underline bool
+	Blink     bool
+}
+
+type CursorChange struct {
+	Was, Now CursorState
+}
+
+// A GlyphChange is a custom character's pattern being redefined, as 5 column bytes.
+type GlyphChange struct {
+	Char     byte
+	Was, Now []byte
+}
+
+// Touches returns whether any changed cell is within cells, e.g. the main screen's frequency
+// digits at image.Rect(1, 2, 10, 3), in the same coordinates as CellChange.Pos.
+func (c *Changed) Touches(cells image.Rectangle) bool {
+	for _, cell := range c.Cells {
+		if cell.Pos.In(cells) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Changed) String() string {
+	var parts []string
+	for _, cell := range c.Cells {
+		parts = append(parts, fmt.Sprintf("cell %d,%d %q>%q", cell.Pos.X, cell.Pos.Y, cell.Was, cell.Now))
+	}
+	if c.Cursor != nil {
+		parts = append(parts, fmt.Sprintf("cursor %v>%v", c.Cursor.Was, c.Cursor.Now))
+	}
+	for _, glyph := range c.Glyphs {
+		parts = append(parts, fmt.Sprintf("glyph %d", glyph.Char))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (s CursorState) String() string {
+	style := "hidden"
+	switch {
+	case s.Underline && s.Blink:
+		style = "underline+blink"
+	case s.Underline:
+		style = "underline"
+	case s.Blink:
+		style = "blink"
+	}
+	return fmt.Sprintf("%d,%d %s", s.Pos.X, s.Pos.Y, style)
+}
+
+// A frame is what the display showed at one moment.
+type frame struct {
+	cells  [][]byte // By row, then column.
+	cursor CursorState
+	glyphs [customChars][]byte
+}
+
+func (d *Decoder) captureFrame() *frame {
+	d.lcdMutex.Lock()
+	defer d.lcdMutex.Unlock()
+
+	f := &frame{cells: make([][]byte, d.lcd.Rows)}
+	ddRam := d.lcd.DisplayRam()
+	for row := range f.cells {
+		f.cells[row] = make([]byte, d.lcd.Cols)
+		for col := range f.cells[row] {
+			f.cells[row][col] = ddRam[d.lcd.DataOffset(row, col)]
+		}
+	}
+	f.cursor.Pos = d.cursorPos()
+	f.cursor.Underline, f.cursor.Blink = d.lcd.CursorStyle()
+	for c := range f.glyphs {
+		f.glyphs[c] = append([]byte{}, d.lcd.CharBits(byte(c))...)
+	}
+	return f
+}
+
+// diff returns what changed from f to next, or nil if nothing did.
+func (f *frame) diff(next *frame) *Changed {
+	changed := &Changed{}
+	for row := range next.cells {
+		for col, now := range next.cells[row] {
+			if was := f.cells[row][col]; was != now {
+				changed.Cells = append(changed.Cells, CellChange{image.Point{X: col, Y: row + 1}, was, now})
+			}
+		}
+	}
+	if f.cursor != next.cursor {
+		changed.Cursor = &CursorChange{f.cursor, next.cursor}
+	}
+	for c := range next.glyphs {
+		if !bytes.Equal(f.glyphs[c], next.glyphs[c]) {
+			changed.Glyphs = append(changed.Glyphs, GlyphChange{byte(c), f.glyphs[c], next.glyphs[c]})
+		}
+	}
+	if changed.Cells == nil && changed.Cursor == nil && changed.Glyphs == nil {
+		return nil
+	}
+	return changed
+}
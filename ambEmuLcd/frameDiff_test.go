@@ -0,0 +1,93 @@
+package ambEmuLcd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"uSDX/hd44780"
+)
+
+// Screens that the uSDX draws one after another, each left to settle, with the Changed events
+// that the decoder should send for them. An empty Changes entry means no Changed event.
+
+// A changeFixture is a list of streams, each followed by a quiet period, and the Changed
+// events, as strings, that should follow each one's Settled event. A "*" stands for any
+// Changed event.
+type changeFixture struct {
+	name    string
+	streams [][]byte
+	changes []string
+}
+
+var changeFixtures = []changeFixture{
+	{"main screen", [][]byte{usdxInit(), usdxMainScreen()},
+		[]string{"", `cell 0,2 ' '>'\x06'; cell 2,2 ' '>'7'; cell 3,2 ' '>','; cell 4,2 ' '>'0'; cell 5,2 ' '>'7'; ` +
+			`cell 6,2 ' '>'4'; cell 7,2 ' '>','; cell 8,2 ' '>'0'; cell 9,2 ' '>'0'; ` +
+			`cell 11,2 ' '>'U'; cell 12,2 ' '>'S'; cell 13,2 ' '>'B'; cursor 0,1 hidden>16,2 hidden`}},
+
+	{"redrawn unchanged", [][]byte{usdxInit(), usdxMainScreen(), usdxMainScreen()},
+		[]string{"", "*", ""}},
+
+	{"one digit", [][]byte{usdxInit(), usdxMainScreen(), stream(moveTo(2, 5), text("8"))},
+		[]string{"", "*", `cell 5,2 '7'>'8'; cursor 16,2 hidden>6,2 hidden`}},
+
+	{"cursor shown", [][]byte{usdxInit(), stream(moveTo(2, 6), nibbles(CMD_REGISTER, hd44780.CmdDisplay|hd44780.CmdDisplayOn|hd44780.CmdDisplayCursor))},
+		[]string{"", "cursor 0,1 hidden>6,2 underline"}},
+
+	{"glyph redefined", [][]byte{usdxInit(), stream(nibbles(CMD_REGISTER, hd44780.CmdSetCgramAddr|2*8), text("\x1f\x1f\x1f\x1f\x1f\x1f\x1f\x1f"), moveTo(1, 0))},
+		[]string{"", "glyph 2"}},
+}
+
+// moveTo returns the command that moves the cursor to col, on row counting from 1.
+func moveTo(row, col int) []byte {
+	return nibbles(CMD_REGISTER, hd44780.CmdSetDdramAddr|byte((row-1)*0x40+col))
+}
+
+func TestChanged(t *testing.T) {
+	for _, f := range changeFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			if err := checkChangeFixture(f); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func checkChangeFixture(f changeFixture) error {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDecoder(16, 2, hd44780.RomA02)
+	d.Clock = func() time.Time { return now }
+	InitUsdxGlyphs(d.lcd)
+	d.settledFrame = d.captureFrame()
+
+	var changes []string
+	done := make(chan bool)
+	go func() {
+		for e := range d.Events {
+			switch e := e.(type) {
+			case *Settled:
+				changes = append(changes, "")
+			case *Changed:
+				changes[len(changes)-1] = e.String()
+			}
+		}
+		done <- true
+	}()
+	for _, s := range f.streams {
+		d.Feed(s)
+		now = now.Add(d.QuietPeriod)
+		d.CheckSettled()
+	}
+	close(d.Events)
+	<-done
+
+	if len(changes) != len(f.changes) {
+		return fmt.Errorf("%d settles, want %d", len(changes), len(f.changes))
+	}
+	for i, want := range f.changes {
+		if changes[i] != want && !(want == "*" && changes[i] != "") {
+			return fmt.Errorf("stream %d changed %s, want %s", i, changes[i], want)
+		}
+	}
+	return nil
+}
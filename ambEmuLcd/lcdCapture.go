@@ -0,0 +1,113 @@
+package ambEmuLcd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// The raw bytes that the controller board sends can be captured to a file, one JSON record per
+// read, and replayed later through a Decoder in place of the serial port. A capture reproduces
+// exactly what the board sent, including the idle gaps that the Settled events come from.
+
+// An lcdCaptureRecord is one read from the controller board. Data is empty if the read timed
+// out, i.e. the board had gone idle.
+type lcdCaptureRecord struct {
+	Elapsed time.Duration `json:"elapsed"` // Since the capture started, in ns
+	Data    string        `json:"data,omitempty"`
+}
+
+// idleReadTimeout stands in for the serial port's read timeout once a replay has run out.
+const idleReadTimeout = 50 * time.Millisecond
+
+// CaptureTo starts recording everything ProcessSerialLcdData reads to path.
+func (d *Decoder) CaptureTo(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	d.capture = json.NewEncoder(f)
+	d.captureStart = time.Now()
+	return nil
+}
+
+// captureRead records a read, if capturing. Only the first of a run of idle reads is recorded.
+func (d *Decoder) captureRead(data []byte, wasIdle bool) {
+	if d.capture == nil || (len(data) == 0 && wasIdle) {
+		return
+	}
+	record := lcdCaptureRecord{Elapsed: time.Since(d.captureStart), Data: hex.EncodeToString(data)}
+	if err := d.capture.Encode(&record); err != nil {
+		log.Printf("LCD capture: %v", err)
+		d.capture = nil
+	}
+}
+
+// A ReplaySource reads a capture back as if it were the controller board's serial port,
+// with reads that time out where the board went idle.
+type ReplaySource struct {
+	records  []lcdCaptureRecord
+	next     int
+	realTime bool          // Whether to pace the reads as they were captured, or only pause where the board went idle.
+	idleGap  time.Duration // How long a replay that isn't in real time pauses where the board went idle.
+	start    time.Time
+}
+
+// NewReplaySource returns a ReplaySource for the capture at path, to be decoded by a Decoder with
+// the given QuietPeriod. A replay that isn't in real time pauses for twice that where the board
+// went idle, so that the decoder sends the Settled event. Once the capture runs out, the source
+// stays idle, like a radio that's been switched off.
+func NewReplaySource(path string, realTime bool, quietPeriod time.Duration) (*ReplaySource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src := &ReplaySource{realTime: realTime, idleGap: 2 * quietPeriod}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record lcdCaptureRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		if _, err := hex.DecodeString(record.Data); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		src.records = append(src.records, record)
+	}
+	return src, scanner.Err()
+}
+
+// Read returns the next captured read, which is empty for an idle one. buf must be at least
+// as big as the captured reads, which ProcessSerialLcdData's buffer is.
+func (src *ReplaySource) Read(buf []byte) (int, error) {
+	if src.next >= len(src.records) {
+		time.Sleep(idleReadTimeout)
+		return 0, nil
+	}
+	if src.start.IsZero() {
+		src.start = time.Now()
+	}
+	record := src.records[src.next]
+	src.next++
+	data, _ := hex.DecodeString(record.Data)
+	if src.realTime {
+		time.Sleep(time.Until(src.start.Add(record.Elapsed)))
+	} else if len(data) == 0 {
+		time.Sleep(src.idleGap)
+	}
+	if len(data) > len(buf) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(buf, data), nil
+}
@@ -0,0 +1,122 @@
+package ambEmuLcd
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"uSDX/hd44780"
+)
+
+var updateSettled = flag.Bool("update", false, "Rewrite the Settled screens expected from the captures in testdata")
+
+// The captures in testdata/captures are replayed through a decoder, and the screens of its
+// Settled events are compared with those in the .settled file alongside each one. Captures from
+// a radio, made with -lcd-capture, are named after the firmware version on its splash screen.
+// Those named usdxSim-* were recorded from the simulator, so they check how the decoder handles
+// captures, not how the firmware lays out its screens.
+func TestReplayCaptures(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "captures", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captures) == 0 {
+		t.Fatal("no captures in testdata")
+	}
+	for _, capture := range captures {
+		t.Run(filepath.Base(capture), func(t *testing.T) {
+			screens, err := replayCapture(capture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(screens, "\n") + "\n"
+			golden := strings.TrimSuffix(capture, ".jsonl") + ".settled"
+			if *updateSettled {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("settled on:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// TestCapturesFromRadio reports, as a skip, that there's no capture from a radio, so the decoder
+// hasn't been checked against a real controller board's power-up and init sequence. Captures
+// made with -lcd-capture, named after the firmware version on the splash screen, fix that.
+func TestCapturesFromRadio(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "captures", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, capture := range captures {
+		if !strings.HasPrefix(filepath.Base(capture), "usdxSim-") {
+			return
+		}
+	}
+	t.Skip("no captures from a radio")
+}
+
+// replayCapture feeds the capture at path to a new Decoder, at the times it was captured, and
+// returns the screens of its Settled events. As ProcessSerialLcdData would, it checks whether
+// the display has settled before each read, and once the capture has run out.
+func replayCapture(path string) ([]string, error) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	d := NewDecoder(16, 2, hd44780.RomA02)
+	d.Clock = func() time.Time { return now }
+	src, err := NewReplaySource(path, false, d.QuietPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	var screens []string
+	done := make(chan bool)
+	go func() {
+		for e := range d.Events {
+			if e, ok := e.(*Settled); ok {
+				screens = append(screens, fmt.Sprintf("%q %q %d,%d", e.Line1Data, e.Line2Data, e.CursorPos.X, e.CursorPos.Y))
+			}
+		}
+		done <- true
+	}()
+	for _, record := range src.records {
+		now = start.Add(record.Elapsed)
+		d.CheckSettled()
+		data, _ := hex.DecodeString(record.Data)
+		if len(data) > 0 {
+			d.Feed(data)
+		}
+	}
+	now = now.Add(d.QuietPeriod)
+	d.CheckSettled()
+	close(d.Events)
+	<-done
+	return screens, nil
+}
+
+func TestReplaySourceIdleGap(t *testing.T) {
+	const quietPeriod = 200 * time.Millisecond
+	src, err := NewReplaySource(filepath.Join("testdata", "captures", "usdxSim-power-up-and-tune.jsonl"), false, quietPeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if n, err := src.Read(make([]byte, 128)); n != 0 || err != nil {
+		t.Fatalf("first read got %d bytes, %v, want an idle read", n, err)
+	}
+	if paused := time.Since(start); paused < 2*quietPeriod {
+		t.Errorf("idle read paused for %v, want at least %v", paused, 2*quietPeriod)
+	}
+}
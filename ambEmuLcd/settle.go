@@ -0,0 +1,51 @@
+package ambEmuLcd
+
+import "time"
+
+// The controller board sends the uSDX's LCD writes as they happen, so a screen is complete once
+// they pause. A pause of 50 ms has always been long enough, and the uSDX's steady trickle of
+// s-meter updates leaves plenty of them.
+const (
+	DefaultQuietPeriod      = 50 * time.Millisecond
+	DefaultMaxSettleLatency = 500 * time.Millisecond
+)
+
+// CheckSettled sends a Settled event if bytes have been decoded since the last one and none
+// have arrived for the QuietPeriod. ProcessSerialLcdData calls it when the QuietPeriod is up;
+// a caller of Feed calls it themselves.
+func (d *Decoder) CheckSettled() {
+	now := d.Clock()
+	if d.unsettledBytes == 0 || now.Sub(d.lastByteArrived) < d.QuietPeriod {
+		return
+	}
+
+	// A pause means that the controller board has finished sending, so the decoder can't be
+	// part way through a write.
+	d.flushEightBitWrite()
+	d.state = WAITING_FOR_NIBBLE_1
+
+	d.settle(now)
+}
+
+// untilSettled is how long from now CheckSettled should next be called.
+func (d *Decoder) untilSettled() time.Duration {
+	if d.unsettledBytes == 0 {
+		return d.QuietPeriod
+	}
+	return d.lastByteArrived.Add(d.QuietPeriod).Sub(d.Clock())
+}
+
+func (d *Decoder) settle(now time.Time) {
+	e := d.createSettledEvent()
+	e.Time = now
+	e.Bytes = d.unsettledBytes
+	d.unsettledBytes = 0
+	d.Events <- e
+
+	frame := d.captureFrame()
+	if changed := d.settledFrame.diff(frame); changed != nil {
+		changed.Time = now
+		d.Events <- changed
+	}
+	d.settledFrame = frame
+}
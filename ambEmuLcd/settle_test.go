@@ -0,0 +1,103 @@
+package ambEmuLcd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"uSDX/hd44780"
+)
+
+// Timed streams of writes from the controller board, with the Settled events that the decoder
+// should send for them under the default QuietPeriod and MaxSettleLatency. The decoder is given
+// a fake clock, so the timing is exact.
+
+// A settleFixture is a list of steps, each at a time since the start, and the Settled events
+// that they should produce.
+type settleFixture struct {
+	name    string
+	steps   []settleStep
+	settles []settleWant
+}
+
+// A settleStep feeds data to the decoder or, if data is nil, calls CheckSettled.
+type settleStep struct {
+	at   time.Duration
+	data []byte
+}
+
+type settleWant struct {
+	at    time.Duration
+	bytes int
+}
+
+var settleFixtures = []settleFixture{
+	{"nothing to settle", []settleStep{{0, nil}, {time.Second, nil}},
+		nil},
+
+	{"quiet period", []settleStep{{0, usdxMainScreen()}, {49 * time.Millisecond, nil}, {50 * time.Millisecond, nil}, {time.Second, nil}},
+		[]settleWant{{50 * time.Millisecond, len(usdxMainScreen())}}},
+
+	{"quiet period from last byte", []settleStep{{0, text("1")}, {40 * time.Millisecond, text("2")}, {50 * time.Millisecond, nil}, {90 * time.Millisecond, nil}},
+		[]settleWant{{90 * time.Millisecond, 4}}},
+
+	{"pause mid byte", []settleStep{{0, text("1")[:1]}, {50 * time.Millisecond, nil}, {60 * time.Millisecond, text("2")}, {110 * time.Millisecond, nil}},
+		[]settleWant{{50 * time.Millisecond, 1}, {110 * time.Millisecond, 2}}},
+
+	{"max latency", meterTrickle(600*time.Millisecond, 10*time.Millisecond),
+		[]settleWant{{500 * time.Millisecond, 102}, {640 * time.Millisecond, 18}}},
+}
+
+// meterTrickle is an s-meter glyph written every interval, for d, then a check for the quiet period.
+func meterTrickle(d, interval time.Duration) []settleStep {
+	var steps []settleStep
+	for at := time.Duration(0); at < d; at += interval {
+		steps = append(steps, settleStep{at, nibbles(DATA_REGISTER, 3)})
+	}
+	return append(steps, settleStep{d + 50*time.Millisecond - interval, nil})
+}
+
+func TestSettle(t *testing.T) {
+	for _, f := range settleFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			if err := checkSettleFixture(f); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func checkSettleFixture(f settleFixture) error {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	d := NewDecoder(16, 2, hd44780.RomA02)
+	d.Clock = func() time.Time { return now }
+
+	var got []settleWant
+	done := make(chan bool)
+	go func() {
+		for e := range d.Events {
+			if settled, ok := e.(*Settled); ok {
+				got = append(got, settleWant{settled.Time.Sub(start), settled.Bytes})
+			}
+		}
+		done <- true
+	}()
+	for _, step := range f.steps {
+		now = start.Add(step.at)
+		if step.data == nil {
+			d.CheckSettled()
+		} else {
+			d.Feed(step.data)
+		}
+	}
+	close(d.Events)
+	<-done
+
+	if fmt.Sprint(got) != fmt.Sprint(f.settles) {
+		return fmt.Errorf("settled at %v, want %v", got, f.settles)
+	}
+	if d.Resyncs() != 0 {
+		return fmt.Errorf("%d resyncs, want 0", d.Resyncs())
+	}
+	return nil
+}
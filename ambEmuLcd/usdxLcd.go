@@ -1,12 +1,10 @@
 package ambEmuLcd
 
-// #include "vrEmuLcd.h"
-import "C"
-
 const glyphCount = 8
 const rowsPerGlyph = 8
 
-var glyph = [glyphCount][rowsPerGlyph]uint8{
+// UsdxGlyphs are the custom characters that the uSDX firmware defines, for codes 1 to 8.
+var UsdxGlyphs = [glyphCount][rowsPerGlyph]uint8{
 	{0b01000, // 1; logo
 		0b00100,
 		0b01010,
@@ -82,14 +80,14 @@ var glyph = [glyphCount][rowsPerGlyph]uint8{
 }
 
 func createGlyph(lcd AmbEmuLcd, n int, aGlyph [rowsPerGlyph]uint8) {
-	C.vrEmuLcdSendCommand(lcd, C.uchar(0x40|(((n+1)&0x7)<<3)))
+	lcd.SendCommand(byte(0x40 | (((n + 1) & 0x7) << 3)))
 	for i := 0; i != rowsPerGlyph; i++ {
-		C.vrEmuLcdWriteByte(lcd, C.uchar(aGlyph[i]))
+		lcd.WriteData(aGlyph[i])
 	}
 }
 
 func InitUsdxGlyphs(lcd AmbEmuLcd) {
 	for i := 0; i < glyphCount; i++ {
-		createGlyph(lcd, i, glyph[i])
+		createGlyph(lcd, i, UsdxGlyphs[i])
 	}
 }
@@ -1,53 +1,148 @@
 package main
 
-import "C"
-
 import (
+	"context"
+	"flag"
+	"fmt"
 	"gioui.org/app"
 	"github.com/tarm/serial"
+	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 	"uSDX/ambEmuLcd"
 	"uSDX/controls"
+	"uSDX/hd44780"
+	"uSDX/usdxSim"
 )
 
 const uSdxDev = "/dev/serial/by-id/usb-Arduino_LLC_Arduino_Nano_Every_ACB4982851514746334C2020FF0E2053-if00"
 
+var usdxLcd = ambEmuLcd.NewDecoder(16, 2, hd44780.RomA02)
+
+var catTranscript = flag.String("cat-transcript", "", "File to record CAT sessions to")
+var catReplay = flag.String("cat-replay", "", "CAT transcript to replay against a mock uSDX, instead of running the app")
+var catGolden = flag.String("cat-golden", "", "Golden transcript to compare the -cat-replay responses with")
+var catGoldenUpdate = flag.Bool("cat-golden-update", false, "Write the -cat-replay responses to the -cat-golden file")
+var uSdxDevice = flag.String("usdx", uSdxDev, "Serial device of the uSDX controller board")
+var uSdxSim = flag.Bool("usdx-sim", false, "Connect to a simulated uSDX instead of the controller board")
+var menuCache = flag.String("menu-cache", defaultMenuCache(), "File to cache the uSDX's menu in, by firmware, or empty to discover it every time")
+var profileSave = flag.String("profile-save", "", "Take a profile of the uSDX's settings, save it to this file, and exit")
+var profileDiff = flag.String("profile-diff", "", "Report how the uSDX's settings differ from the profile in this file, and exit")
+var profileRestore = flag.String("profile-restore", "", "Restore the uSDX's settings that differ from the profile in this file, and exit")
+var profileOtherFirmware = flag.Bool("profile-other-firmware", false, "Let -profile-restore restore a profile taken from different firmware")
+var lcdCapture = flag.String("lcd-capture", "", "File to capture the raw LCD data from the controller board to")
+var lcdReplay = flag.String("lcd-replay", "", "LCD capture to replay instead of connecting to the controller board")
+var lcdReplayFast = flag.Bool("lcd-replay-fast", false, "Replay the -lcd-replay capture with its idle gaps shortened, rather than in real time")
+var lcdQuietPeriod = flag.Duration("lcd-quiet", ambEmuLcd.DefaultQuietPeriod, "How long the LCD data must pause for the display to be taken as settled")
+var lcdMaxSettleLatency = flag.Duration("lcd-settle-max", ambEmuLcd.DefaultMaxSettleLatency, "The longest the display is left unsettled while LCD data keeps arriving")
+var catEndpoints = flag.String("cat", controls.DefaultCatEndpoints, "Comma separated CAT endpoints, e.g. pty:ttyUSDX1,civ,addr=94@tcp:localhost:7374")
+var rigctldAddr = flag.String("rigctld", "", "Address to serve Hamlib's rigctld protocol on, e.g. "+controls.RigctldAddr+", or empty for none")
+var flrigAddr = flag.String("flrig", "", "Address to serve flrig's XML-RPC interface on, e.g. "+controls.FlrigAddr+", or empty for none")
+
 func main() {
 
-	lcdEvents := make(chan interface{}, 100)
+	flag.Parse()
+	if *catReplay != "" {
+		if err := controls.ReplayCatTranscript(*catReplay, *catGolden, *catGoldenUpdate); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("CAT replay of %s matches %s", *catReplay, *catGolden)
+		return
+	}
 
-	go gui(loop, lcdEvents)
+	endpoints, catErr := controls.ParseCatEndpoints(*catEndpoints)
+	if catErr != nil {
+		log.Fatal(catErr)
+	}
 
-	uSdxConfig := &serial.Config{Name: uSdxDev, Baud: 500000, ReadTimeout: 50 * time.Millisecond}
-	uSdxPort, uSdxErr := serial.OpenPort(uSdxConfig)
-	if uSdxErr != nil {
-		log.Fatal(uSdxErr)
+	if *catTranscript != "" {
+		if err := controls.RecordCatTranscript(*catTranscript); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	usdxLcd.QuietPeriod = *lcdQuietPeriod
+	usdxLcd.MaxSettleLatency = *lcdMaxSettleLatency
+	var lcdSource io.Reader
+	if *lcdReplay != "" {
+		replay, replayErr := ambEmuLcd.NewReplaySource(*lcdReplay, !*lcdReplayFast, usdxLcd.QuietPeriod)
+		if replayErr != nil {
+			log.Fatal(replayErr)
+		}
+		lcdSource = replay
+		controls.InitLowLevelControls(ioutil.Discard) // There's no radio to operate.
+	} else if *uSdxSim {
+		sim, simPort := usdxSim.Loopback()
+		lcdSource = simPort
+		controls.InitLowLevelControls(simPort)
+		sim.PowerOn()
+	} else {
+		uSdxConfig := &serial.Config{Name: *uSdxDevice, Baud: 500000, ReadTimeout: 50 * time.Millisecond}
+		uSdxPort, uSdxErr := serial.OpenPort(uSdxConfig)
+		if uSdxErr != nil {
+			log.Fatal(uSdxErr)
+		}
+		lcdSource = uSdxPort
+		controls.InitLowLevelControls(uSdxPort)
 	}
-	controls.InitLowLevelControls(uSdxPort)
-	controls.InitHighLevelControls()
+	if *lcdCapture != "" {
+		if err := usdxLcd.CaptureTo(*lcdCapture); err != nil {
+			log.Fatal(err)
+		}
+	}
+	controls.InitHighLevelControls(*menuCache)
+
+	if *profileSave != "" || *profileDiff != "" || *profileRestore != "" {
+		go usdxLcd.ProcessSerialLcdData(lcdSource)
+		go func() {
+			for e := range usdxLcd.Events {
+				handleLcdEvent(e)
+			}
+		}()
+		if err := runProfileCommand(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	go gui(loop, usdxLcd.Events)
 	controls.ForceRefresh()
-	go ambEmuLcd.ProcessSerialLcdData(uSdxPort, lcdEvents)
+	go usdxLcd.ProcessSerialLcdData(lcdSource)
 
-	// TODO: Use ProcessPtyCat where Pty is available (e.g. Linux/Mac), else ProcessSerialCat (e.g. Windows)
-	go controls.ProcessPtyCat()
+	// Use pty endpoints where Pty is available (e.g. Linux/Mac), else serial endpoints (e.g. Windows)
+	controls.ProcessCatEndpoints(endpoints)
+	if *rigctldAddr != "" {
+		go controls.ProcessRigctld(*rigctldAddr)
+	}
+	if *flrigAddr != "" {
+		go controls.ProcessFlrig(*flrigAddr)
+	}
 
 	app.Main()
 
 }
 
+func defaultMenuCache() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".uSDX-menu.json")
+}
+
 func loop(w *app.Window, lcdEvents chan interface{}) error {
 	for {
 		select {
 
 		case lcdEvt := <-lcdEvents:
 			w.Invalidate()
-			switch e := lcdEvt.(type) {
-			case *ambEmuLcd.Settled:
-				controls.HandleSettledEvent(e)
-			case ambEmuLcd.Updated:
-				// Nothing yet. Might not use.
-			}
+			handleLcdEvent(lcdEvt)
+
+		case <-controls.OperationsChanged():
+			w.Invalidate()
 
 		case e := <-w.Events():
 			stop, evtErr := handleWindowEvent(e)
@@ -57,3 +152,61 @@ func loop(w *app.Window, lcdEvents chan interface{}) error {
 		}
 	}
 }
+
+func handleLcdEvent(lcdEvt interface{}) {
+	switch e := lcdEvt.(type) {
+	case *ambEmuLcd.Settled:
+		controls.HandleSettledEvent(e)
+	case ambEmuLcd.Updated:
+		// Nothing yet. Might not use.
+	case ambEmuLcd.PoweredOn:
+		controls.HandlePowerEvent(true)
+	case ambEmuLcd.PoweredOff:
+		controls.HandlePowerEvent(false)
+	}
+}
+
+// runProfileCommand carries out the -profile-save, -profile-diff or -profile-restore flag.
+func runProfileCommand() error {
+	ctx := context.Background()
+	if err := controls.RefreshScreen(ctx); err != nil {
+		return err
+	}
+	switch {
+	case *profileSave != "":
+		p, err := controls.TakeProfile(ctx, controls.ProfileName(*profileSave))
+		if err != nil {
+			return err
+		}
+		if err := controls.SaveProfile(p, *profileSave); err != nil {
+			return err
+		}
+		log.Printf("Saved profile %s, with %d settings", p.Name, len(p.Settings))
+
+	case *profileDiff != "":
+		p, err := controls.LoadProfile(*profileDiff)
+		if err != nil {
+			return err
+		}
+		diffs, err := controls.DiffProfile(ctx, p)
+		if err != nil {
+			return err
+		}
+		fmt.Print(controls.DiffReport(p, diffs))
+
+	case *profileRestore != "":
+		p, err := controls.LoadProfile(*profileRestore)
+		if err != nil {
+			return err
+		}
+		printReport := func(report string) bool {
+			fmt.Print(report)
+			return true
+		}
+		if err := controls.RestoreProfile(ctx, p, *profileOtherFirmware, printReport); err != nil {
+			return err
+		}
+		log.Printf("Restored profile %s", p.Name)
+	}
+	return nil
+}
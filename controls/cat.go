@@ -3,11 +3,8 @@ package controls
 import (
 	"bufio"
 	"fmt"
-	"github.com/tarm/serial"
-	"log"
-	"os"
+	"strconv"
 	"strings"
-	"uSDX/pty"
 )
 
 // Based, in part, on code from https://github.com/threeme3/QCX-SSB.
@@ -19,221 +16,256 @@ import (
 // Emulated radio is documented at:
 //    https://www.kenwood.com/i/products/info/amateur/ts_480/pdf/ts_480_pc.pdf
 
-var catFile *os.File = nil
-var catSerial *serial.Port = nil
+// kenwoodDialect is the CAT dialect of the emulated TS-480.
+type kenwoodDialect struct{}
 
-func ProcessSerialCat(_catSerial *serial.Port) {
-	catConfig := &serial.Config{Name: "/dev/ttyS21", Baud: 9600}
-	_catSerial, catErr := serial.OpenPort(catConfig)
-	if catErr != nil {
-		log.Fatal(catErr) // Comment out if you're not going to do CAT
-	}
-	catSerial = _catSerial
-
-	catReader := bufio.NewReader(catSerial)
-	for {
-		data, err := catReader.ReadBytes(';')
-		if err != nil {
-			log.Fatal(err)
-		}
-		processCatCommand(data)
-	}
+func (kenwoodDialect) spec() string {
+	return "kenwood"
 }
 
-func ProcessPtyCat() {
-	const catPttyLink = "ttyUSDX1"
+func (kenwoodDialect) readCommand(r *bufio.Reader) ([]byte, error) {
+	return r.ReadBytes(';')
+}
 
-	_catFile, catTty, ptyErr := pty.Open()
-	if ptyErr != nil {
-		log.Fatal(ptyErr)
-	}
-	catFile = _catFile
-
-	homeDir, _ := os.UserHomeDir()
-	fq := homeDir + "/" + catPttyLink
-	_ = os.Remove(fq)
-	linkErr := os.Symlink(catTty, fq)
-	if linkErr != nil {
-		log.Fatal(linkErr)
-	}
+func (kenwoodDialect) describe(cmd []byte) string {
+	return string(cmd)
+}
 
-	catReader := bufio.NewReader(catFile)
-	for {
-		data, err := catReader.ReadBytes(';')
-		if err != nil {
-			log.Fatal(err)
-		}
-		processCatCommand(data)
+func (kenwoodDialect) undescribe(desc string) ([]byte, error) {
+	return []byte(desc), nil
+}
+
+func (kenwoodDialect) execute(client *catClient, catCmd []byte) []byte {
+	catCaller = client
+	defer func() { catCaller = nil }()
+	return []byte(executeCatCommand(catCmd))
+}
+
+const (
+	digits       = "0123456789"
+	signedDigits = "+-0123456789"
+)
+
+func init() {
+	RegisterCatCommand(&CatCommand{Name: "AI", Read: readAutoInfo, SetWidth: 1, SetChars: "012", Set: setAutoInfo})
+	RegisterCatCommand(&CatCommand{Name: "CH", SetWidth: 1, SetChars: "01", Set: setMultiChannel})
+	RegisterCatCommand(&CatCommand{Name: "DN", Read: setMicDown})
+	RegisterCatCommand(&CatCommand{Name: "FA", Read: readFrequencyA, SetWidth: 11, SetChars: digits, SetValid: frequencyParamValid, Set: setFrequencyA})
+	RegisterCatCommand(&CatCommand{Name: "FB", Read: readFrequencyB, SetWidth: 11, SetChars: digits, SetValid: frequencyParamValid, Set: setFrequencyB})
+	RegisterCatCommand(&CatCommand{Name: "FR", Read: readReceiverVfo, SetWidth: 1, SetChars: "01", Set: setReceiverVfo})
+	RegisterCatCommand(&CatCommand{Name: "FT", Read: readTransmitterVfo, SetWidth: 1, SetChars: "01", Set: setTransmitterVfo})
+	RegisterCatCommand(&CatCommand{Name: "ID", Read: readTransceiverId})
+	RegisterCatCommand(&CatCommand{Name: "IF", Read: readTransceiverStatus})
+	RegisterCatCommand(&CatCommand{Name: "MD", Read: readOperatingMode, SetWidth: 1, SetChars: "12345", Set: setOperatingMode})
+	RegisterCatCommand(&CatCommand{Name: "PS", Read: readPowerOnOffStatus, SetWidth: 1, SetChars: "1", Set: setPowerOnOffStatus})
+	RegisterCatCommand(&CatCommand{Name: "RS", Read: readTranceiverStatus})
+	RegisterCatCommand(&CatCommand{Name: "RX", Read: setReceiveMode})
+	RegisterCatCommand(&CatCommand{Name: "SM", ReadForm: "0", Read: readSMeter})
+	RegisterCatCommand(&CatCommand{Name: "TX", Read: setTransmitMode, SetWidth: 1, SetChars: "012", Set: setTransmitModeP1})
+	RegisterCatCommand(&CatCommand{Name: "UP", Read: setMicUp})
+	RegisterCatCommand(&CatCommand{Name: "VX", Read: readVoxStatus, SetWidth: 1, SetChars: "01", Set: setVoxStatus})
+}
+
+func readFrequencyA() string {
+	return fmt.Sprintf("FA00%09d;", rig.frequencyA)
+}
+
+func setFrequencyA(p string) string {
+	if rig.vfo != vfoA {
+		return catSyntaxError
 	}
+	setFrequencyParam(p)
+	return ""
+}
+
+// The uSDX displays frequencies to the nearest 10 Hz, in 7 digits, so it can only be tuned
+// between these. The CAT servers reject frequencies outside them.
+const (
+	minFrequency = 10
+	maxFrequency = 99999990
+)
+
+func frequencyInRange(hz int64) bool {
+	return hz >= minFrequency && hz <= maxFrequency
+}
+
+func frequencyParamValid(p string) bool {
+	hz, err := strconv.ParseInt(p, 10, 64)
+	return err == nil && frequencyInRange(hz)
 }
 
-func processCatCommand(catCmd []byte) {
-
-	log.Printf("CMD %s", string(catCmd))
-
-	noParams := catCmd[2] == ';'
-
-	switch string(catCmd[0:2]) {
-
-	case "AI":
-		if noParams {
-			readAutoInfo()
-			return
-		} else if catCmd[2] == '0' {
-			setAutoInfo(0)
-			return
-		}
-
-	case "FA":
-		if noParams {
-			readFrequencyA()
-			return
-		} else if catCmd[13] == ';' {
-			setFrequencyA(catCmd)
-			return
-		}
-
-	case "ID":
-		if noParams {
-			readTransceiverId()
-			return
-		}
-
-	case "IF":
-		if noParams {
-			readTransceiverStatus()
-			return
-		}
-
-	case "MD":
-		if noParams {
-			readOperatingMode()
-			return
-		}
-
-	case "PS":
-		if noParams {
-			readPowerOnOffStatus()
-			return
-		} else if catCmd[2] == '1' {
-			setPowerOnOffStatus('1')
-			return
-		}
-
-	case "RS":
-		if noParams {
-			readTranceiverStatus()
-			return
-		}
-
-	case "RX":
-		if noParams {
-			setReceiveMode()
-			return
-		}
-
-	case "TX":
-		if noParams {
-			setTransmitMode(0)
-			return
-		} else if strings.ContainsAny(string(catCmd[2]), "012") {
-			setTransmitMode(catCmd[2])
-			return
-		}
-
-	case "VX":
-		if catCmd[2] != ';' {
-			setVoxStatus(catCmd[2])
-			return
-		}
+// p is an 11 digit frequency in Hz, as validated by the parser.
+func setFrequencyParam(p string) {
+	hz, _ := strconv.ParseInt(p, 10, 64)
+	backend.setFrequency(hz)
+}
+
+// The uSDX can only tune the VFO that it is displaying, so FA and FB are only applied while
+// their VFO is on screen. Otherwise the set is refused, and FB keeps reporting the last value
+// seen.
+func readFrequencyB() string {
+	return fmt.Sprintf("FB00%09d;", rig.frequencyB)
+}
 
+func setFrequencyB(p string) string {
+	if rig.vfo != vfoB {
+		return catSyntaxError
 	}
-	respond("?;")
+	setFrequencyParam(p)
+	return ""
+}
+
+// The uSDX receives and transmits on the displayed VFO. Whether it's in split operation can't
+// be told from the display, so it's reported as simplex. Switching VFOs isn't possible from
+// the controller board, so only sets of the displayed VFO are accepted.
+func readReceiverVfo() string {
+	return fmt.Sprintf("FR%d;", rig.vfo)
+}
+
+func setReceiverVfo(p string) string {
+	return setDisplayedVfo(p)
+}
+
+func readTransmitterVfo() string {
+	return fmt.Sprintf("FT%d;", rig.vfo)
+}
 
+func setTransmitterVfo(p string) string {
+	return setDisplayedVfo(p)
 }
 
-func respond(response string) {
-	log.Printf("RSP %s", response)
-	_, err := catFile.Write([]byte(response))
-	if err != nil {
-		log.Fatal(err)
+func setDisplayedVfo(p string) string {
+	if p != fmt.Sprintf("%d", rig.vfo) {
+		return catSyntaxError
 	}
+	return ""
+}
+
+// TS-480 reports S9 as 15 and S9+60dB as 30. The uSDX s-meter stops at S9+.
+func readSMeter() string {
+	return fmt.Sprintf("SM0%04d;", rig.sMeter*15/9)
 }
 
-func readFrequencyA() {
-	respond(fmt.Sprintf("FA00%09d;", currentFrequencyA))
+// The microphone UP/DN keys and the MULTI/CH knob all map to the uSDX's encoder.
+func setMicUp() string {
+	backend.rotateEncoder(1)
+	return ""
 }
 
-func setFrequencyA(catCmd []byte) {
-	SetFrequency(string(catCmd[2:13]))
+func setMicDown() string {
+	backend.rotateEncoder(-1)
+	return ""
+}
+
+func setMultiChannel(p string) string {
+	if p == "0" {
+		backend.rotateEncoder(1)
+	} else {
+		backend.rotateEncoder(-1)
+	}
+	return ""
 }
 
 func add(sb *strings.Builder, addition string) {
 	sb.WriteString(addition)
 }
 
-func readTransceiverStatus() {
+func boolDigit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func readTransceiverStatus() string {
 	var sb strings.Builder
 
 	add(&sb, "IF")
-	add(&sb, fmt.Sprintf("00%09d", currentFrequencyA))
-	add(&sb, "     ") // P2   Always 5 spaces for TS-480
-	add(&sb, "+0000") // P3   RIT/XIT frequency in Hz
-	add(&sb, "0")     // P4   0: RIT OFF, 1: RIT ON
-	add(&sb, "0")     // P5   0: XIT OFF, 1: XIT ON
-	add(&sb, "0")     // P6   Memory channel bank number
-	add(&sb, "00")    // P7   Memory channel number
-	add(&sb, "0")     // P8   0:RX, 1:TX  Why does QCX-SSB always have 0?
-	add(&sb, "2")     // P9   Operating Mode. Why does QCX-SSB always have 2?
-	add(&sb, "0")     // P10  "See FR and FT commands"?
-	add(&sb, "0")     // P11  Scan status
-	add(&sb, "0")     // P12  0: Simplex Operation, 1: Split operation
-	add(&sb, "0")     // P13  0: OFF, 1: TONE, 2: CTCSS
-	add(&sb, "00")    // P14  Tone number, refer to the TN anc CN commands
-	add(&sb, " ")     // P15  Always a space for TS-480
+	add(&sb, fmt.Sprintf("00%09d", rig.displayedVfoFrequency()))
+	add(&sb, "     ")                     // P2   Always 5 spaces for TS-480
+	add(&sb, "+0000")                     // P3   RIT/XIT frequency in Hz
+	add(&sb, "0")                         // P4   0: RIT OFF, 1: RIT ON
+	add(&sb, "0")                         // P5   0: XIT OFF, 1: XIT ON
+	add(&sb, "0")                         // P6   Memory channel bank number
+	add(&sb, "00")                        // P7   Memory channel number
+	add(&sb, boolDigit(rig.transmitting)) // P8   0:RX, 1:TX
+	add(&sb, fmt.Sprintf("%d", rig.mode)) // P9   Operating Mode, as in MD command
+	add(&sb, fmt.Sprintf("%d", rig.vfo))  // P10  0: VFO A, 1: VFO B, as in FR and FT commands
+	add(&sb, "0")                         // P11  Scan status
+	add(&sb, "0")                         // P12  0: Simplex Operation, 1: Split operation
+	add(&sb, "0")                         // P13  0: OFF, 1: TONE, 2: CTCSS
+	add(&sb, "00")                        // P14  Tone number, refer to the TN anc CN commands
+	add(&sb, " ")                         // P15  Always a space for TS-480
 	add(&sb, ";")
 
-	respond(sb.String())
+	return sb.String()
+}
+
+func readAutoInfo() string {
+	if catCaller == nil {
+		return "AI0;" // OFF
+	}
+	return fmt.Sprintf("AI%c;", catCaller.autoInfo)
+}
+
+// AI1 and AI2 both turn on auto-information. The distinction between them concerns the
+// TS-480's memory backup of the setting, which doesn't apply here.
+func setAutoInfo(p string) string {
+	if catCaller != nil {
+		catCaller.autoInfo = p[0]
+	}
+	return ""
+}
+
+func readOperatingMode() string {
+	return fmt.Sprintf("MD%d;", rig.mode)
 }
 
-func readAutoInfo() {
-	respond("AI0;") // OFF
+func setOperatingMode(p string) string {
+	backend.setMode(int(p[0] - '0'))
+	return ""
 }
 
-func setAutoInfo(p1 byte) {
-	respond("AI0;")
+func setReceiveMode() string {
+	backend.setPushToTalk(false)
+	return "" // REVIEW: Why does a set command have a response? QCX-SSB answered "RX0;"
 }
 
-func readOperatingMode() {
-	respond("MD2;") // TODO: Why does QCX-SSB always return USB?
+func setTransmitMode() string {
+	backend.setPushToTalk(true)
+	return ""
 }
 
-func setReceiveMode() {
-	EndPushToTalk()
-	//respond("RX0;") // REVIEW: Why does a set command have a response?
+func setTransmitModeP1(p string) string {
+	return setTransmitMode()
 }
 
-func setTransmitMode(p1 byte) {
-	StartPushToTalk()
+func readTranceiverStatus() string {
+	return "RS0;"
 }
 
-func readTranceiverStatus() {
-	respond("RS0;")
+func readVoxStatus() string {
+	return "VX0;" // uSDX has no VOX
 }
 
-func setVoxStatus(p1 byte) {
+func setVoxStatus(p string) string {
 	// TODO: Why was QCX-SSB implementation answering with given byte?
-	// respond(fmt.Sprintf("VX%c;", p1))
+	// return fmt.Sprintf("VX%s;", p)
+	return ""
 }
 
-func readTransceiverId() {
-	respond("ID020;")
+func readTransceiverId() string {
+	return "ID020;"
 }
 
-func readPowerOnOffStatus() {
-	respond("PS1;")
+func readPowerOnOffStatus() string {
+	if !rig.poweredOn {
+		return "PS0;"
+	}
+	return "PS1;"
 }
 
-func setPowerOnOffStatus(p1 byte) {
+func setPowerOnOffStatus(p string) string {
 	// REVIEW: No implementation provided in QCX-SSB
+	return ""
 }
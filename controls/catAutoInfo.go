@@ -0,0 +1,94 @@
+package controls
+
+// Kenwood's "auto-information" lets a client subscribe to changes instead of polling for them.
+// When a client has set AI1 or AI2, each change to frequency, mode or TX state that shows up in a
+// Settled event is pushed to it, unsolicited, as FA or FB, MD and IF messages.
+
+var catClients []*catClient // All connected CAT clients. Guarded by rigMutex.
+
+var catCaller *catClient // The client whose command is being executed. Guarded by rigMutex.
+
+// The rig state that auto-information reports on.
+type autoInfoState struct {
+	frequencyA int64
+	frequencyB int64
+	vfo        int
+	mode       string
+	ptt        bool
+}
+
+var lastAutoInfoState autoInfoState
+
+func addCatClient(client *catClient) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	catClients = append(catClients, client)
+}
+
+func removeCatClient(client *catClient) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	for i, c := range catClients {
+		if c == client {
+			catClients = append(catClients[:i], catClients[i+1:]...)
+			return
+		}
+	}
+}
+
+func currentAutoInfoState() autoInfoState {
+	return autoInfoState{
+		frequencyA: rig.frequencyA,
+		frequencyB: rig.frequencyB,
+		vfo:        rig.vfo,
+		mode:       readOperatingMode(),
+		ptt:        rig.transmitting,
+	}
+}
+
+// autoInfoMessages compares the rig state with that of the previous call, and returns the
+// messages that describe the differences. Must be called with rigMutex held.
+func autoInfoMessages() []string {
+	curr := currentAutoInfoState()
+	prev := lastAutoInfoState
+	lastAutoInfoState = curr
+
+	if curr == prev {
+		return nil
+	}
+	var msgs []string
+	if curr.frequencyA != prev.frequencyA {
+		msgs = append(msgs, readFrequencyA())
+	}
+	if curr.frequencyB != prev.frequencyB {
+		msgs = append(msgs, readFrequencyB())
+	}
+	if curr.mode != prev.mode {
+		msgs = append(msgs, curr.mode)
+	}
+	return append(msgs, readTransceiverStatus())
+}
+
+// autoInfoSubscribers returns the clients that have turned on auto-information.
+// Must be called with rigMutex held.
+func autoInfoSubscribers() []*catClient {
+	var subscribers []*catClient
+	for _, c := range catClients {
+		if c.autoInfo != '0' {
+			subscribers = append(subscribers, c)
+		}
+	}
+	return subscribers
+}
+
+// pushAutoInfo queues msgs for each of the subscribers. It's called without rigMutex held, and
+// doesn't wait for the messages to be written, so that a slow client can't stall the LCD
+// decoder or the other clients.
+func pushAutoInfo(subscribers []*catClient, msgs []string) {
+	for _, c := range subscribers {
+		for _, msg := range msgs {
+			recordCatExchange(c, nil, []byte(msg), nil)
+			respond(c, []byte(msg))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package controls
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// A stalledConn is a client that has stopped reading, so every write blocks.
+type stalledConn struct {
+	io.Reader
+	writing chan bool // Receives a value as each write starts.
+	unblock chan bool
+}
+
+func (c stalledConn) Write(p []byte) (int, error) {
+	c.writing <- true
+	<-c.unblock
+	return len(p), nil
+}
+
+func TestPushAutoInfoDoesntWaitForClients(t *testing.T) {
+	stalled := stalledConn{writing: make(chan bool, 1), unblock: make(chan bool)}
+	defer close(stalled.unblock)
+	client := &catClient{name: "stalled", rw: stalled, dialect: kenwoodDialect{}, autoInfo: '1'}
+	client.outbound = make(chan []byte, catOutboundLen)
+	done := make(chan bool)
+	defer close(done)
+	go client.writeOutbound(done)
+
+	// The writer takes the first message off the queue, and blocks writing it.
+	pushAutoInfo([]*catClient{client}, []string{"FA00007074000;"})
+	<-stalled.writing
+
+	pushed := make(chan bool)
+	go func() {
+		for i := 0; i < 2*catOutboundLen; i++ {
+			pushAutoInfo([]*catClient{client}, []string{"FA00007074000;"})
+		}
+		pushed <- true
+	}()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("pushAutoInfo waited for a client that isn't reading")
+	}
+	if len(client.outbound) != catOutboundLen {
+		t.Errorf("%d messages queued, want %d", len(client.outbound), catOutboundLen)
+	}
+}
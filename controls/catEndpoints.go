@@ -0,0 +1,272 @@
+package controls
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/tarm/serial"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"uSDX/pty"
+)
+
+// DefaultCatEndpoints is the endpoint list used when none is configured.
+const DefaultCatEndpoints = "pty:ttyUSDX1"
+
+// A CatEndpoint is a place where CAT clients can connect, written as "[dialect@]kind:where".
+// The kinds are:
+//
+//	pty:NAME     A pty, symlinked as NAME in the user's home directory (Linux/Mac)
+//	tcp:ADDR     A TCP listener on ADDR, e.g. localhost:7373, accepting any number of clients
+//	serial:DEV   A serial device, e.g. a com0com port (Windows)
+//
+// The dialects are:
+//
+//	kenwood                  Kenwood TS-480, the default
+//	civ[,addr=XX][,echo]     Icom CI-V, answering to hex address XX (default 94), optionally echoing commands
+//	ft817                    Yaesu FT-817/818
+type CatEndpoint struct {
+	Dialect string
+	Kind    string
+	Where   string
+}
+
+// A catDialect is a CAT protocol that clients can speak.
+type catDialect interface {
+	spec() string // As given to newCatDialect
+	readCommand(r *bufio.Reader) ([]byte, error)
+	describe(cmd []byte) string // For logging and transcripts
+	undescribe(desc string) ([]byte, error)
+	execute(client *catClient, cmd []byte) []byte
+}
+
+// A catClient is one program talking CAT to the app, over whatever transport.
+type catClient struct {
+	name     string
+	rw       io.ReadWriter
+	dialect  catDialect
+	autoInfo byte        // The client's Kenwood AI setting, '0' to '2'.
+	outbound chan []byte // Responses and auto-information waiting to be written to rw.
+}
+
+// catOutboundLen is how many messages can wait to be written to a client. A client that falls
+// this far behind, e.g. one that has stopped reading its pty, loses the messages that follow.
+const catOutboundLen = 64
+
+// ParseCatEndpoints parses a comma separated list of endpoints, e.g. "pty:ttyUSDX1,tcp:localhost:7373".
+// Because the civ dialect's options are also comma separated, an item that follows one without
+// a ':' continues it.
+func ParseCatEndpoints(list string) ([]CatEndpoint, error) {
+	var specs []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if len(specs) > 0 && !strings.Contains(specs[len(specs)-1], ":") {
+			specs[len(specs)-1] += "," + item
+		} else {
+			specs = append(specs, item)
+		}
+	}
+
+	var endpoints []CatEndpoint
+	for _, spec := range specs {
+		ep := CatEndpoint{Dialect: "kenwood"}
+		kindAndWhere := spec
+		if at := strings.Index(spec, "@"); at >= 0 {
+			ep.Dialect = spec[:at]
+			kindAndWhere = spec[at+1:]
+		}
+		parts := strings.SplitN(kindAndWhere, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("bad CAT endpoint %q", spec)
+		}
+		ep.Kind, ep.Where = parts[0], parts[1]
+		switch ep.Kind {
+		case "pty", "tcp", "serial":
+		default:
+			return nil, fmt.Errorf("unknown kind of CAT endpoint %q", spec)
+		}
+		if _, err := newCatDialect(ep.Dialect); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+func newCatDialect(spec string) (catDialect, error) {
+	options := strings.Split(spec, ",")
+	switch options[0] {
+	case "kenwood":
+		if len(options) > 1 {
+			return nil, fmt.Errorf("kenwood CAT dialect has no options: %q", spec)
+		}
+		return kenwoodDialect{}, nil
+	case "civ":
+		d := &civDialect{address: civDefaultAddress}
+		for _, opt := range options[1:] {
+			switch {
+			case opt == "echo":
+				d.echo = true
+			case strings.HasPrefix(opt, "addr="):
+				addr, err := strconv.ParseUint(strings.TrimPrefix(opt, "addr="), 16, 8)
+				if err != nil || addr == 0 || addr >= civPreamble {
+					return nil, fmt.Errorf("bad CI-V address in %q", spec)
+				}
+				d.address = byte(addr)
+			default:
+				return nil, fmt.Errorf("unknown CI-V option %q", opt)
+			}
+		}
+		return d, nil
+	case "ft817":
+		if len(options) > 1 {
+			return nil, fmt.Errorf("ft817 CAT dialect has no options: %q", spec)
+		}
+		return ft817Dialect{}, nil
+	}
+	return nil, fmt.Errorf("unknown CAT dialect %q", spec)
+}
+
+// ProcessCatEndpoints serves CAT on each of the endpoints, each in its own goroutine.
+// Every client's responses go back to that client. The clients' commands are executed one at a
+// time, under rigMutex, and the operations of the radio that they start are queued with the
+// scheduler, which also runs them one at a time.
+func ProcessCatEndpoints(endpoints []CatEndpoint) {
+	for _, ep := range endpoints {
+		dialect, err := newCatDialect(ep.Dialect)
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch ep.Kind {
+		case "pty":
+			go ProcessPtyCat(ep.Where, dialect)
+		case "tcp":
+			go ProcessTcpCat(ep.Where, dialect)
+		case "serial":
+			go ProcessSerialCat(ep.Where, dialect)
+		}
+	}
+}
+
+// ProcessSerialCat serves CAT on the serial device devName, e.g. a com0com port on Windows.
+func ProcessSerialCat(devName string, dialect catDialect) {
+	catConfig := &serial.Config{Name: devName, Baud: 9600}
+	catSerial, catErr := serial.OpenPort(catConfig)
+	if catErr != nil {
+		log.Fatal(catErr) // Comment out if you're not going to do CAT
+	}
+	serveCatClient(&catClient{name: devName, rw: catSerial, dialect: dialect})
+}
+
+// ProcessPtyCat serves CAT on a new pty, which is symlinked as catPtyLink in the user's home directory.
+// When the program on the other end closes it, reads fail with EIO, so a new pty is opened in its
+// place, for the next program to open through the same symlink.
+func ProcessPtyCat(catPtyLink string, dialect catDialect) {
+	homeDir, _ := os.UserHomeDir()
+	fq := homeDir + "/" + catPtyLink
+	for {
+		catFile := openCatPty(fq)
+		serveCatClient(&catClient{name: fq, rw: catFile, dialect: dialect})
+		catFile.Close()
+		log.Printf("CAT pty %s was closed, replacing it", fq)
+	}
+}
+
+// openCatPty opens a pty in raw mode, so that binary dialects pass through it unchanged, and
+// symlinks it as link.
+func openCatPty(link string) *os.File {
+	catFile, catTty, ptyErr := pty.Open()
+	if ptyErr != nil {
+		log.Fatal(ptyErr)
+	}
+	if err := pty.MakeRaw(catFile); err != nil {
+		log.Fatal(err)
+	}
+	_ = os.Remove(link)
+	linkErr := os.Symlink(catTty, link)
+	if linkErr != nil {
+		log.Fatal(linkErr)
+	}
+	return catFile
+}
+
+// ProcessTcpCat accepts CAT clients on addr, serving each one in its own goroutine.
+func ProcessTcpCat(addr string, dialect catDialect) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			defer conn.Close()
+			serveCatClient(&catClient{name: conn.RemoteAddr().String(), rw: conn, dialect: dialect})
+		}()
+	}
+}
+
+func serveCatClient(client *catClient) {
+	log.Printf("CAT client %s connected", client.name)
+	client.autoInfo = '0'
+	client.outbound = make(chan []byte, catOutboundLen)
+	done := make(chan bool)
+	go client.writeOutbound(done)
+	defer close(done)
+	addCatClient(client)
+	defer removeCatClient(client)
+
+	catReader := bufio.NewReader(client.rw)
+	for {
+		data, err := client.dialect.readCommand(catReader)
+		if err != nil {
+			log.Printf("CAT client %s: %v", client.name, err)
+			return
+		}
+		processCatCommand(client, data)
+	}
+}
+
+func processCatCommand(client *catClient, catCmd []byte) {
+	log.Printf("CMD %s %s", client.name, client.dialect.describe(catCmd))
+	rigMutex.Lock()
+	before := transcriptRigIfRecording()
+	response := client.dialect.execute(client, catCmd)
+	rigMutex.Unlock()
+	recordCatExchange(client, catCmd, response, before)
+	if len(response) > 0 {
+		respond(client, response)
+	}
+}
+
+// respond queues response to be written to client, without waiting for it to be written.
+func respond(client *catClient, response []byte) {
+	log.Printf("RSP %s %s", client.name, client.dialect.describe(response))
+	select {
+	case client.outbound <- response:
+	default:
+		log.Printf("CAT client %s isn't reading, so %s was dropped", client.name, client.dialect.describe(response))
+	}
+}
+
+// writeOutbound writes the client's queued messages, in order, until done is closed.
+func (client *catClient) writeOutbound(done chan bool) {
+	for {
+		select {
+		case msg := <-client.outbound:
+			if _, err := client.rw.Write(msg); err != nil {
+				log.Printf("CAT client %s: %v", client.name, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
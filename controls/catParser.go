@@ -0,0 +1,93 @@
+package controls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kenwood error responses
+const (
+	catSyntaxError = "?;" // Unknown command, or bad parameters
+	catCommError   = "E;" // Command couldn't be framed
+)
+
+const catMaxCommandLen = 64 // Longest legitimate TS-480 command is KY with 24 chars of text.
+
+// CatCommand describes the grammar of one Kenwood CAT command, and the functions that carry it out.
+// Handlers return the response to send to the client, or "" if there is none.
+type CatCommand struct {
+	Name     string                // Two uppercase letters, e.g. "FA".
+	ReadForm string                // The parameters of the read form, usually "". Ignored if Read is nil.
+	Read     func() string         // Handles the read form, or a parameterless action like "RX;".
+	SetWidth int                   // Length of the set form's parameters. -1 accepts any length.
+	SetChars string                // The characters allowed in the set form's parameters. "" allows any.
+	SetValid func(p string) bool   // Further checks the set form's parameters, e.g. a range. Nil accepts any.
+	Set      func(p string) string // Handles the set form. Receives the parameters without the name or ';'.
+}
+
+var catCommands = map[string]*CatCommand{}
+
+// RegisterCatCommand adds a command to those understood by the CAT parser.
+// It panics if the command is malformed or its name is already registered.
+func RegisterCatCommand(cmd *CatCommand) {
+	if len(cmd.Name) != 2 || strings.ToUpper(cmd.Name) != cmd.Name {
+		panic(fmt.Sprintf("Bad CAT command name %q", cmd.Name))
+	}
+	if cmd.Read == nil && cmd.Set == nil {
+		panic(fmt.Sprintf("CAT command %s has no handlers", cmd.Name))
+	}
+	if cmd.Set != nil && cmd.SetWidth == 0 {
+		panic(fmt.Sprintf("CAT command %s has a zero width set form", cmd.Name))
+	}
+	if _, found := catCommands[cmd.Name]; found {
+		panic(fmt.Sprintf("CAT command %s is already registered", cmd.Name))
+	}
+	catCommands[cmd.Name] = cmd
+}
+
+// executeCatCommand validates catCmd against the registered grammars and runs the matching handler.
+// It returns the response for the client, which is an error response if catCmd is malformed.
+func executeCatCommand(catCmd []byte) string {
+	s := strings.TrimLeft(string(catCmd), " \r\n")
+
+	if len(s) > catMaxCommandLen || !strings.HasSuffix(s, ";") {
+		return catCommError
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return catCommError
+		}
+	}
+	if len(s) < 3 {
+		return catSyntaxError
+	}
+
+	cmd, found := catCommands[s[0:2]]
+	if !found {
+		return catSyntaxError
+	}
+	params := s[2 : len(s)-1]
+
+	if cmd.Read != nil && params == cmd.ReadForm {
+		return cmd.Read()
+	}
+	if cmd.Set != nil && cmd.setFormAccepts(params) {
+		return cmd.Set(params)
+	}
+	return catSyntaxError
+}
+
+func (cmd *CatCommand) setFormAccepts(params string) bool {
+	if cmd.SetWidth >= 0 && len(params) != cmd.SetWidth {
+		return false
+	}
+	if params == "" {
+		return false
+	}
+	for _, c := range params {
+		if cmd.SetChars != "" && !strings.ContainsRune(cmd.SetChars, c) {
+			return false
+		}
+	}
+	return cmd.SetValid == nil || cmd.SetValid(params)
+}
@@ -0,0 +1,180 @@
+package controls
+
+import "testing"
+
+func TestRegisterCatCommandPanics(t *testing.T) {
+	read := func() string { return "" }
+	set := func(p string) string { return "" }
+	cases := []struct {
+		desc string
+		cmd  *CatCommand
+	}{
+		{"lowercase name", &CatCommand{Name: "zz", Read: read}},
+		{"short name", &CatCommand{Name: "Z", Read: read}},
+		{"no handlers", &CatCommand{Name: "ZZ"}},
+		{"zero width set form", &CatCommand{Name: "ZZ", Set: set}},
+		{"already registered", &CatCommand{Name: "FA", Read: read}},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RegisterCatCommand didn't panic", c.desc)
+				}
+			}()
+			RegisterCatCommand(c.cmd)
+		}()
+	}
+}
+
+func TestRegisterCatCommand(t *testing.T) {
+	var got string
+	RegisterCatCommand(&CatCommand{
+		Name:     "ZZ",
+		ReadForm: "0",
+		Read:     func() string { return "ZZ0;" },
+		SetWidth: 2,
+		SetChars: digits,
+		SetValid: func(p string) bool { return p != "99" },
+		Set:      func(p string) string { got = p; return "" },
+	})
+	defer delete(catCommands, "ZZ")
+
+	cases := []struct{ cmd, want string }{
+		{"ZZ0;", "ZZ0;"},
+		{"ZZ;", catSyntaxError},
+		{"ZZ12;", ""},
+		{"ZZ1;", catSyntaxError},
+		{"ZZ123;", catSyntaxError},
+		{"ZZ1A;", catSyntaxError},
+		{"ZZ99;", catSyntaxError},
+	}
+	for _, c := range cases {
+		if response := executeCatCommand([]byte(c.cmd)); response != c.want {
+			t.Errorf("%s got %q, want %q", c.cmd, response, c.want)
+		}
+	}
+	if got != "12" {
+		t.Errorf("set handler got %q, want \"12\"", got)
+	}
+}
+
+func TestExecuteCatCommand(t *testing.T) {
+	savedRig, savedBackend := rig, backend
+	defer func() {
+		rig, backend = savedRig, savedBackend
+		resetCatStandIns()
+	}()
+	backend = mockBackend{}
+
+	cases := []struct{ cmd, want string }{
+		// Framing
+		{"", catCommError},
+		{"FA", catCommError},
+		{"FA\x00;", catCommError},
+		{"FA00000000000000000000000000000000000000000000000000000000000000000;", catCommError},
+		{";", catSyntaxError},
+		{"F;", catSyntaxError},
+		{"ZY;", catSyntaxError},
+		{"\r\nID;", "ID020;"},
+
+		// Reads and sets
+		{"FA00014074000;", ""},
+		{"FA;", "FA00014074000;"},
+		{"MD3;", ""},
+		{"MD;", "MD3;"},
+		{"MD6;", catSyntaxError},
+		{"SM0;", "SM00000;"},
+		{"SM;", catSyntaxError},
+		{"AG0;", "AG0100;"},
+
+		// Stand-ins' sets, which take digits, and a sign for IS
+		{"PC0x5;", catSyntaxError},
+		{"AG0zz9;", catSyntaxError},
+		{"PC010;", ""},
+		{"PC;", "PC010;"},
+		{"IS-0300;", ""},
+		{"IS;", "IS-0300;"},
+		{"IS 03-0;", catSyntaxError},
+		{"IS+0300;", ""},
+		{"IS;", "IS+0300;"},
+
+		// The VFO that isn't displayed, which can't be set
+		{"FB00007074000;", catSyntaxError},
+		{"FR1;", catSyntaxError},
+		{"FT1;", catSyntaxError},
+		{"FR0;", ""},
+		{"FT0;", ""},
+
+		// Out of range frequencies, which the uSDX can't display
+		{"FA00000000000;", catSyntaxError},
+		{"FA00000000009;", catSyntaxError},
+		{"FA00100000000;", catSyntaxError},
+		{"FA0001407400A;", catSyntaxError},
+		{"FA;", "FA00014074000;"},
+	}
+	rig = rigState{vfo: vfoA, mode: modeUsb, poweredOn: true}
+	for _, c := range cases {
+		if response := executeCatCommand([]byte(c.cmd)); response != c.want {
+			t.Errorf("%q got %q, want %q", c.cmd, response, c.want)
+		}
+	}
+}
+
+func TestMenuBackedStandIns(t *testing.T) {
+	savedBackend, savedItems := backend, menuItems
+	defer func() {
+		backend, menuItems = savedBackend, savedItems
+		resetCatStandIns()
+	}()
+	backend = mockBackend{}
+
+	menuItems = nil
+	if response := executeCatCommand([]byte("KS;")); response != "KS020;" {
+		t.Errorf("KS; got %q before the menu is known, want the stand-in's \"KS020;\"", response)
+	}
+
+	menuItems = []MenuItem{
+		{Name: "Volume", Value: "8"},
+		{Name: "Filter BW", Value: "Full"},
+		{Name: "AGC", Value: "ON"},
+		{Name: "NR", Value: "0"},
+		{Name: "ATT", Value: "0dB"},
+		{Name: "Keyer speed", Value: "25"},
+	}
+	cases := []struct{ cmd, want string }{
+		{"AG0;", "AG0128;"},
+		{"AG0255;", ""},
+		{"AG0;", "AG0255;"},
+		{"AG0256;", catSyntaxError},
+		{"FW;", "FW0000;"},
+		{"FW2400;", ""},
+		{"FW;", "FW2400;"},
+		{"GT;", "GT002;"},
+		{"GT005;", ""},
+		{"GT;", "GT005;"},
+		{"GT000;", ""},
+		{"GT;", "GT000;"},
+		{"KS;", "KS025;"},
+		{"KS030;", ""},
+		{"KS;", "KS030;"},
+		{"NR;", "NR0;"},
+		{"NR2;", ""},
+		{"NR;", "NR1;"},
+		{"NR3;", catSyntaxError},
+		{"RA;", "RA0000;"},
+		{"RA01;", ""},
+		{"RA;", "RA0100;"},
+	}
+	for _, c := range cases {
+		if response := executeCatCommand([]byte(c.cmd)); response != c.want {
+			t.Errorf("%q got %q, want %q", c.cmd, response, c.want)
+		}
+	}
+	want := map[string]string{"Volume": "16", "Filter BW": "2400", "AGC": "OFF", "NR": "1", "ATT": "-13dB", "Keyer speed": "30"}
+	for _, item := range menuItems {
+		if item.Value != want[item.Name] {
+			t.Errorf("%s is %q, want %q", item.Name, item.Value, want[item.Name])
+		}
+	}
+}
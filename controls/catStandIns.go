@@ -0,0 +1,312 @@
+package controls
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Most of the TS-480 command set has no counterpart on the uSDX, or refers to state that
+// the LCD doesn't show. Rather than answering "?;", which makes many programs give up on
+// the radio, these commands are backed by stand-in values. Reads answer with the stand-in
+// value and sets replace it, so a client always reads back what it last set. The uSDX
+// itself is never touched by these commands, except those for its menu settings, below.
+
+type standIn struct {
+	read  string // The answer's parameters, excluding the leading command name.
+	width int    // Length of a set's parameters. 0: can't be set, -1: any length, discarded.
+}
+
+var catStandIns = map[string]*standIn{
+	"AC": {"000", 3},       // Antenna tuner: off
+	"AG": {"0100", 4},      // AF gain. First digit is always 0.
+	"AN": {"1", 1},         // Antenna 1
+	"BC": {"0", 1},         // Beat cancel: off
+	"BY": {"00", 0},        // Not busy
+	"CA": {"0", 1},         // CW auto zero-beat: off
+	"CN": {"00", 2},        // CTCSS tone number
+	"CT": {"0", 1},         // CTCSS: off
+	"FS": {"0", 1},         // Fine tuning: off
+	"FV": {"1.00", 0},      // Firmware version
+	"FW": {"0000", 4},      // DSP filter width
+	"GT": {"002", 3},       // AGC time constant: slow
+	"IS": {" 0000", 5},     // IF shift
+	"KS": {"020", 3},       // Keying speed, WPM
+	"KY": {"0", -1},        // Keyer buffer has space. Text to send is discarded.
+	"LK": {"00", 2},        // Lock: off
+	"LM": {"0", 1},         // Voice recording: off
+	"MC": {"000", 3},       // Memory channel
+	"MF": {"0", 1},         // Menu A
+	"MG": {"050", 3},       // Mic gain
+	"ML": {"000", 3},       // TX monitor level
+	"NB": {"0", 1},         // Noise blanker: off
+	"NL": {"001", 3},       // Noise blanker level
+	"NR": {"0", 1},         // Noise reduction: off
+	"NT": {"0", 1},         // Notch: off
+	"OF": {"000000000", 9}, // Offset frequency
+	"OS": {"0", 1},         // Offset: simplex
+	"PA": {"00", 1},        // Pre-amp: off. Second digit is always 0.
+	"PB": {"0", 1},         // Voice playback: off
+	"PC": {"005", 3},       // Output power, watts
+	"PR": {"0", 1},         // Speech processor: off
+	"QR": {"00", 2},        // Quick memory: off
+	"RA": {"0000", 2},      // Attenuator: off. Last two digits are always 00.
+	"RG": {"100", 3},       // RF gain
+	"RL": {"00", 2},        // Noise reduction level
+	"RM": {"10000", 1},     // Meter: SWR, reading 0
+	"RT": {"0", 1},         // RIT: off
+	"SC": {"00", 2},        // Scan: off
+	"SD": {"0000", 4},      // CW break-in delay
+	"SH": {"00", 2},        // DSP slope high
+	"SL": {"00", 2},        // DSP slope low
+	"SQ": {"0000", 4},      // Squelch. First digit is always 0.
+	"TN": {"00", 2},        // Tone number
+	"TO": {"0", 1},         // Tone: off
+	"TS": {"0", 1},         // TF-SET: off
+	"TY": {"K00", 0},       // Firmware type
+	"UL": {"0", 0},         // PLL locked
+	"VD": {"0000", 4},      // VOX delay
+	"VG": {"000", 3},       // VOX gain
+	"XT": {"0", 1},         // XIT: off
+}
+
+// These are actions rather than settings, so they are accepted and ignored.
+var catIgnoredActions = []string{
+	"BD", // Band down
+	"BU", // Band up
+	"MW", // Memory write
+	"QI", // Quick memory store
+	"RC", // RIT clear
+	"RD", // RIT down
+	"RU", // RIT up
+	"SR", // Reset
+	"SV", // Memory transfer
+	"VR", // Voice synthesis
+}
+
+// The stand-ins' values as the app starts, which resetCatStandIns goes back to.
+var catStandInDefaults = map[string]string{}
+
+func init() {
+	for name, s := range catStandIns {
+		catStandInDefaults[name] = s.read
+		RegisterCatCommand(s.catCommand(name))
+	}
+	for _, name := range catIgnoredActions {
+		RegisterCatCommand(&CatCommand{Name: name, Read: ignoreAction, SetWidth: -1, Set: ignoreActionP})
+	}
+}
+
+// Callers must hold rigMutex.
+func resetCatStandIns() {
+	for name, s := range catStandIns {
+		s.read = catStandInDefaults[name]
+	}
+}
+
+func (s *standIn) catCommand(name string) *CatCommand {
+	cmd := &CatCommand{Name: name, Read: func() string { return name + s.read + ";" }}
+	switch name {
+	case "SQ", "AG":
+		cmd.ReadForm = "0" // These reads carry a "main/sub receiver" digit, which is always 0 on TS-480.
+	}
+	switch {
+	case s.width < 0:
+		cmd.SetWidth = -1
+		cmd.Set = ignoreActionP
+	case s.width > 0:
+		cmd.SetWidth = s.width
+		cmd.SetChars = digits
+		if name == "IS" { // The shift's sign, or a space, then its digits.
+			cmd.SetChars = " " + signedDigits
+			cmd.SetValid = func(p string) bool { return !strings.ContainsAny(p[1:], " +-") }
+		}
+		cmd.Set = func(p string) string {
+			log.Printf("Stand-in %s%s", name, p)
+			s.read = p + s.read[s.width:]
+			return ""
+		}
+	}
+	if m, found := catMenuSettings[name]; found {
+		m.backCommand(cmd, s)
+	}
+	return cmd
+}
+
+// Some of the stand-ins are for settings that the uSDX has on its menu. Once the menu has been
+// discovered, these commands read the menu's value and sets edit it, in the background, so
+// a read shows the new value once the radio has confirmed it.
+type menuSetting struct {
+	setting string                                                 // The menu item's name.
+	read    func(value string, s *standIn) (p string, ok bool)     // The read's parameters.
+	set     func(p, value string, s *standIn) (to string, ok bool) // The menu value to set.
+}
+
+var catMenuSettings = map[string]menuSetting{
+	"AG": {"Volume", readVolume, setVolume},
+	"FW": {"Filter BW", readFilterWidth, setFilterWidth},
+	"GT": {"AGC", readAgc, setAgc},
+	"KS": {"Keyer speed", readKeyerSpeed, setKeyerSpeed},
+	"NR": {"NR", readNoiseReduction, setNoiseReduction},
+	"RA": {"ATT", readAttenuator, setAttenuator},
+}
+
+// backCommand makes cmd use the menu setting, falling back on the stand-in until the menu is
+// known, or if its value can't be understood.
+func (m menuSetting) backCommand(cmd *CatCommand, s *standIn) {
+	standInRead, standInSet := cmd.Read, cmd.Set
+	cmd.Read = func() string {
+		if value, found := menuSettingValue(m.setting); found {
+			if p, ok := m.read(value, s); ok {
+				return cmd.Name + p + ";"
+			}
+		}
+		return standInRead()
+	}
+	cmd.Set = func(p string) string {
+		value, found := menuSettingValue(m.setting)
+		if !found {
+			return standInSet(p)
+		}
+		to, ok := m.set(p, value, s)
+		if !ok {
+			return catSyntaxError
+		}
+		if to != value {
+			backend.setSetting(m.setting, to)
+		}
+		return ""
+	}
+}
+
+// AF gain is 0 to 255, after a 0 digit, and the uSDX's volume -1, which mutes it, to 16.
+func readVolume(value string, s *standIn) (string, bool) {
+	volume, err := strconv.Atoi(value)
+	if err != nil {
+		return "", false
+	}
+	if volume < 0 {
+		volume = 0
+	}
+	return fmt.Sprintf("0%03d", (volume*255+8)/16), true
+}
+
+func setVolume(p, value string, s *standIn) (string, bool) {
+	gain, _ := strconv.Atoi(p)
+	if gain > 255 {
+		return "", false
+	}
+	return strconv.Itoa((gain*16 + 127) / 255), true
+}
+
+// The DSP filter width is in Hz, and 0 for the uSDX's Full.
+func readFilterWidth(value string, s *standIn) (string, bool) {
+	if value == "Full" {
+		return "0000", true
+	}
+	hz, err := strconv.Atoi(value)
+	if err != nil || hz > 9999 {
+		return "", false
+	}
+	return fmt.Sprintf("%04d", hz), true
+}
+
+func setFilterWidth(p, value string, s *standIn) (string, bool) {
+	hz, _ := strconv.Atoi(p)
+	if hz == 0 {
+		return "Full", true
+	}
+	return strconv.Itoa(hz), true
+}
+
+// The AGC time constant is 0 for off. The uSDX's AGC is just on or off, so the constant is kept
+// by the stand-in.
+func readAgc(value string, s *standIn) (string, bool) {
+	switch {
+	case value == "OFF":
+		return "000", true
+	case value != "ON":
+		return "", false
+	case s.read == "000":
+		return catStandInDefaults["GT"], true
+	default:
+		return s.read, true
+	}
+}
+
+func setAgc(p, value string, s *standIn) (string, bool) {
+	if p == "000" {
+		return "OFF", true
+	}
+	s.read = p
+	return "ON", true
+}
+
+func readKeyerSpeed(value string, s *standIn) (string, bool) {
+	wpm, err := strconv.Atoi(value)
+	if err != nil || wpm > 999 {
+		return "", false
+	}
+	return fmt.Sprintf("%03d", wpm), true
+}
+
+func setKeyerSpeed(p, value string, s *standIn) (string, bool) {
+	wpm, _ := strconv.Atoi(p)
+	return strconv.Itoa(wpm), true
+}
+
+// Noise reduction is off, NR1 or NR2. The uSDX's NR is a level, from 0 for off, which NR1 and
+// NR2 leave as it is if it's on, or set to 1.
+func readNoiseReduction(value string, s *standIn) (string, bool) {
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return "", false
+	}
+	return boolDigit(level > 0), true
+}
+
+func setNoiseReduction(p, value string, s *standIn) (string, bool) {
+	switch {
+	case p == "0":
+		return "0", true
+	case p != "1" && p != "2":
+		return "", false
+	case value == "0":
+		return "1", true
+	default:
+		return value, true
+	}
+}
+
+// The attenuator is off or on. The uSDX's ATT has several steps, which on leaves as it is if
+// it's on, or sets to the first.
+const attenuatorOff, attenuatorFirstStep = "0dB", "-13dB"
+
+func readAttenuator(value string, s *standIn) (string, bool) {
+	if value == attenuatorOff {
+		return "0000", true
+	}
+	return "0100", true
+}
+
+func setAttenuator(p, value string, s *standIn) (string, bool) {
+	switch {
+	case p == "00":
+		return attenuatorOff, true
+	case p != "01":
+		return "", false
+	case value == attenuatorOff:
+		return attenuatorFirstStep, true
+	default:
+		return value, true
+	}
+}
+
+func ignoreAction() string {
+	return ""
+}
+
+func ignoreActionP(p string) string {
+	return ""
+}
@@ -0,0 +1,256 @@
+package controls
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CAT sessions can be recorded to a transcript file, one JSON record per line, and replayed later
+// against a mock of the uSDX. Replaying a transcript and comparing its responses with those in a
+// "golden" transcript regression-tests compatibility with the programs that the sessions were
+// recorded from, e.g. WSJT-X, fldigi and Hamlib. Sessions on every kind of endpoint are recorded,
+// including the rigctld and flrig servers.
+
+// catTranscriptRecord is one command and its response, or an unsolicited message.
+// Commands and responses are as given by the dialect's describe().
+type catTranscriptRecord struct {
+	Time     *time.Time     `json:"time,omitempty"` // Omitted in golden transcripts
+	Endpoint string         `json:"endpoint"`
+	Dialect  string         `json:"dialect"`
+	Command  string         `json:"command,omitempty"` // Omitted for unsolicited auto-information
+	Response string         `json:"response,omitempty"`
+	Rig      *transcriptRig `json:"rig,omitempty"` // The rig state before the command, if known
+}
+
+type transcriptRig struct {
+	FrequencyA   int64 `json:"frequencyA"`
+	FrequencyB   int64 `json:"frequencyB"`
+	Vfo          int   `json:"vfo"`
+	Mode         int   `json:"mode"`
+	Transmitting bool  `json:"transmitting"`
+	SMeter       int   `json:"sMeter"`
+}
+
+func (r *rigState) transcriptRig() *transcriptRig {
+	return &transcriptRig{r.frequencyA, r.frequencyB, r.vfo, r.mode, r.transmitting, r.sMeter}
+}
+
+// Transcripts don't record the power state, so the replayed radio is taken to be on.
+func (t *transcriptRig) rigState() rigState {
+	return rigState{t.FrequencyA, t.FrequencyB, t.Vfo, t.Mode, t.Transmitting, t.SMeter, true}
+}
+
+var catTranscript *json.Encoder // Nil unless a transcript is being recorded.
+var catTranscriptMutex sync.Mutex
+
+// RecordCatTranscript starts recording every CAT client's commands and responses to path.
+func RecordCatTranscript(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	catTranscriptMutex.Lock()
+	catTranscript = json.NewEncoder(f)
+	catTranscriptMutex.Unlock()
+	return nil
+}
+
+// transcriptRigIfRecording returns the rig state to include in a transcript record, or nil
+// if no transcript is being recorded. Must be called with rigMutex held.
+func transcriptRigIfRecording() *transcriptRig {
+	catTranscriptMutex.Lock()
+	defer catTranscriptMutex.Unlock()
+	if catTranscript == nil {
+		return nil
+	}
+	return rig.transcriptRig()
+}
+
+func recordCatExchange(client *catClient, cmd []byte, response []byte, before *transcriptRig) {
+	catTranscriptMutex.Lock()
+	defer catTranscriptMutex.Unlock()
+	if catTranscript == nil {
+		return
+	}
+	now := time.Now()
+	record := catTranscriptRecord{
+		Time:     &now,
+		Endpoint: client.name,
+		Dialect:  client.dialect.spec(),
+		Response: client.dialect.describe(response),
+		Rig:      before,
+	}
+	if cmd != nil {
+		record.Command = client.dialect.describe(cmd)
+	}
+	if err := catTranscript.Encode(&record); err != nil {
+		log.Printf("CAT transcript: %v", err)
+		catTranscript = nil
+	}
+}
+
+// ReplayCatTranscript replays the commands in the transcript at transcriptPath against a mock of
+// the uSDX. If update is true, the replay's responses are written to goldenPath. Otherwise, they
+// are compared with those in goldenPath, and an error describes any differences.
+func ReplayCatTranscript(transcriptPath, goldenPath string, update bool) error {
+	transcript, err := readCatTranscript(transcriptPath)
+	if err != nil {
+		return err
+	}
+	replayed, err := replayCatRecords(transcript)
+	if err != nil {
+		return err
+	}
+	if update {
+		return writeCatTranscript(goldenPath, replayed)
+	}
+	golden, err := readCatTranscript(goldenPath)
+	if err != nil {
+		return err
+	}
+	return compareCatTranscripts(golden, replayed)
+}
+
+func readCatTranscript(path string) ([]catTranscriptRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []catTranscriptRecord
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record catTranscriptRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func writeCatTranscript(path string, records []catTranscriptRecord) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayCatRecords runs the commands in records against mockBackend, returning the replayed
+// commands with the responses they got. Unsolicited messages in records are skipped.
+func replayCatRecords(records []catTranscriptRecord) ([]catTranscriptRecord, error) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+
+	savedRig, savedBackend := rig, backend
+	defer func() { rig, backend = savedRig, savedBackend }()
+	backend = mockBackend{}
+	resetCatState()
+	menuMutex.Lock()
+	savedMenuItems := menuItems
+	menuItems = nil // So that the replay doesn't depend on the menu cache.
+	menuMutex.Unlock()
+	defer func() {
+		menuMutex.Lock()
+		menuItems = savedMenuItems
+		menuMutex.Unlock()
+	}()
+
+	clients := map[string]*catClient{}
+	var replayed []catTranscriptRecord
+	for i, record := range records {
+		if record.Command == "" {
+			continue
+		}
+		client, found := clients[record.Endpoint]
+		if !found {
+			dialect, err := transcriptDialect(record.Dialect)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", i+1, err)
+			}
+			client = &catClient{name: record.Endpoint, dialect: dialect, autoInfo: '0'}
+			clients[record.Endpoint] = client
+		}
+		if record.Rig != nil {
+			rig = record.Rig.rigState()
+		}
+		cmd, err := client.dialect.undescribe(record.Command)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", i+1, err)
+		}
+		response := client.dialect.execute(client, cmd)
+		replayed = append(replayed, catTranscriptRecord{
+			Endpoint: record.Endpoint,
+			Dialect:  record.Dialect,
+			Command:  record.Command,
+			Response: client.dialect.describe(response),
+		})
+	}
+	return replayed, nil
+}
+
+// resetCatState puts everything that CAT commands can change back as it is when the app starts,
+// so that each replay of a transcript starts from the same state. Callers must hold rigMutex.
+func resetCatState() {
+	rig = rigState{vfo: vfoA, mode: modeUsb, poweredOn: true}
+	resetCatStandIns()
+	ft817Locked = false
+	lastAutoInfoState = autoInfoState{}
+	catCaller = nil
+}
+
+// transcriptDialect returns the dialect named spec in a transcript, which may be one of the
+// servers' as well as a CAT endpoint's.
+func transcriptDialect(spec string) (catDialect, error) {
+	switch spec {
+	case "rigctld":
+		return rigctlDialect{}, nil
+	case "flrig":
+		return flrigDialect{}, nil
+	}
+	return newCatDialect(spec)
+}
+
+func compareCatTranscripts(golden, replayed []catTranscriptRecord) error {
+	var diffs []string
+	for i := 0; i < len(golden) || i < len(replayed); i++ {
+		switch {
+		case i >= len(golden):
+			diffs = append(diffs, fmt.Sprintf("record %d: %q is not in the golden transcript", i+1, replayed[i].Command))
+		case i >= len(replayed):
+			diffs = append(diffs, fmt.Sprintf("record %d: %q was not replayed", i+1, golden[i].Command))
+		case golden[i].Command != replayed[i].Command || golden[i].Endpoint != replayed[i].Endpoint:
+			diffs = append(diffs, fmt.Sprintf("record %d: replayed %q but golden has %q", i+1, replayed[i].Command, golden[i].Command))
+		case golden[i].Response != replayed[i].Response:
+			diffs = append(diffs, fmt.Sprintf("record %d: %q got %q, want %q", i+1, replayed[i].Command, replayed[i].Response, golden[i].Response))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("CAT replay differs from golden transcript:\n%s", strings.Join(diffs, "\n"))
+	}
+	return nil
+}
+
+// undescribeHex reverses the "% X" formatting used to describe binary dialects' commands.
+func undescribeHex(desc string) ([]byte, error) {
+	return hex.DecodeString(strings.ReplaceAll(desc, " ", ""))
+}
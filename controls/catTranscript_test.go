@@ -0,0 +1,57 @@
+package controls
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGoldens = flag.Bool("update", false, "Rewrite the golden CAT transcripts in testdata")
+
+// The transcripts in testdata/transcripts were recorded by the app, with -cat-transcript, from
+// scripted sessions on each kind of endpoint, against usdxSim. The scripts follow the commands
+// that Hamlib, WSJT-X and fldigi send, but they aren't sessions of those programs.
+func TestReplayCatTranscripts(t *testing.T) {
+	transcripts, err := filepath.Glob(filepath.Join("testdata", "transcripts", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayed int
+	for _, transcript := range transcripts {
+		if strings.HasSuffix(transcript, ".golden.jsonl") {
+			continue
+		}
+		golden := strings.TrimSuffix(transcript, ".jsonl") + ".golden.jsonl"
+		// Replaying twice checks that a replay doesn't depend on what ran before it.
+		for i := 0; i < 2; i++ {
+			if err := ReplayCatTranscript(transcript, golden, *updateGoldens && i == 0); err != nil {
+				t.Errorf("%s: %v", transcript, err)
+			}
+		}
+		replayed++
+	}
+	if replayed == 0 {
+		t.Error("no transcripts in testdata")
+	}
+}
+
+// TestTranscriptsFromClients reports, as a skip, the programs that no transcript in testdata has
+// been recorded from. A session of one of them, recorded with -cat-transcript and named after
+// it, e.g. wsjtx-kenwood.jsonl, is replayed by TestReplayCatTranscripts once its golden
+// transcript has been written with -update.
+func TestTranscriptsFromClients(t *testing.T) {
+	var missing []string
+	for _, program := range []string{"wsjtx", "fldigi", "hamlib"} {
+		found, err := filepath.Glob(filepath.Join("testdata", "transcripts", program+"-*.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) == 0 {
+			missing = append(missing, program)
+		}
+	}
+	if len(missing) > 0 {
+		t.Skipf("no transcripts recorded from %s", strings.Join(missing, ", "))
+	}
+}
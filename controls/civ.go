@@ -0,0 +1,228 @@
+package controls
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Emulates an Icom rig's CI-V interface. Command frames look like:
+//    FE FE <to> <from> <cmd> [<subcmd>] [<data>...] FD
+// The protocol is documented in the "CI-V Reference Guide" for each Icom rig, e.g. the IC-7300's.
+
+const (
+	civPreamble       = 0xFE
+	civEndOfMessage   = 0xFD
+	civOk             = 0xFB
+	civNg             = 0xFA
+	civBroadcast      = 0x00
+	civDefaultAddress = 0x94 // IC-7300
+)
+
+const civMaxFrameLen = 32
+
+// Icom command numbers
+const (
+	civReadFrequency   = 0x03
+	civReadMode        = 0x04
+	civSetFrequency    = 0x05
+	civSetMode         = 0x06
+	civSelectVfo       = 0x07
+	civSplit           = 0x0F
+	civReadMeter       = 0x15
+	civReadId          = 0x19
+	civTransmit        = 0x1C
+	civVfoFrequency    = 0x25
+	civSubSMeter       = 0x02 // of civReadMeter
+	civSubTransmitting = 0x00 // of civTransmit
+)
+
+// Icom mode numbers, keyed by the Kenwood mode numbers used by rigState.
+var civModes = map[int]byte{
+	modeLsb: 0x00,
+	modeUsb: 0x01,
+	modeAm:  0x02,
+	modeCw:  0x03,
+	modeFm:  0x05,
+}
+
+const civFilter = 0x01 // FIL1, the widest
+
+type civDialect struct {
+	address byte // The rig's address on the CI-V bus
+	echo    bool // Whether each command is echoed before its reply, as on a single wire CI-V bus
+}
+
+// spec gives the address and echo options, so that a transcript replays with the same ones.
+func (d *civDialect) spec() string {
+	s := fmt.Sprintf("civ,addr=%02X", d.address)
+	if d.echo {
+		s += ",echo"
+	}
+	return s
+}
+
+// readCommand returns the next frame, from its preamble to its end-of-message byte inclusive.
+// Noise between frames is discarded.
+func (d *civDialect) readCommand(r *bufio.Reader) ([]byte, error) {
+	for {
+		frame, err := r.ReadBytes(civEndOfMessage)
+		if err != nil {
+			return nil, err
+		}
+		start := strings.Index(string(frame), string([]byte{civPreamble, civPreamble}))
+		if start < 0 {
+			continue
+		}
+		frame = frame[start:]
+		for len(frame) > 2 && frame[2] == civPreamble { // Some controllers send extra preamble bytes.
+			frame = frame[1:]
+		}
+		return frame, nil
+	}
+}
+
+func (d *civDialect) describe(frame []byte) string {
+	return fmt.Sprintf("% X", frame)
+}
+
+func (d *civDialect) undescribe(desc string) ([]byte, error) {
+	return undescribeHex(desc)
+}
+
+func (d *civDialect) execute(client *catClient, frame []byte) []byte {
+	// Shortest frame is FE FE to from cmd FD
+	if len(frame) < 6 || len(frame) > civMaxFrameLen {
+		return nil
+	}
+	to, from, body := frame[2], frame[3], frame[4:len(frame)-1]
+	if to != d.address && to != civBroadcast {
+		return nil // Addressed to some other rig on the bus
+	}
+	if from == d.address {
+		return nil // Our own reply, echoed back by the bus
+	}
+
+	var reply []byte
+	if answer := d.executeCommand(body); answer != nil {
+		reply = append([]byte{civPreamble, civPreamble, from, d.address}, answer...)
+		reply = append(reply, civEndOfMessage)
+	}
+	if d.echo {
+		return append(append([]byte{}, frame...), reply...)
+	}
+	return reply
+}
+
+// executeCommand runs the command in body, which is a frame without its preamble, addresses
+// and end-of-message byte. It returns the body of the reply.
+func (d *civDialect) executeCommand(body []byte) []byte {
+	cmd, data := body[0], body[1:]
+	ok, ng := []byte{civOk}, []byte{civNg}
+
+	switch cmd {
+
+	case civReadFrequency:
+		if len(data) == 0 {
+			return append([]byte{cmd}, civFrequencyBcd(rig.displayedVfoFrequency())...)
+		}
+
+	case civSetFrequency:
+		if hz, valid := civBcdFrequency(data); valid && frequencyInRange(hz) {
+			backend.setFrequency(hz)
+			return ok
+		}
+
+	case civReadMode:
+		if len(data) == 0 {
+			return []byte{cmd, civModes[rig.mode], civFilter}
+		}
+
+	case civSetMode:
+		if len(data) == 1 || len(data) == 2 {
+			for mode, civMode := range civModes {
+				if civMode == data[0] {
+					backend.setMode(mode)
+					return ok
+				}
+			}
+		}
+
+	case civSelectVfo:
+		// The uSDX's VFO can't be switched, so only the displayed VFO can be selected.
+		if len(data) == 0 || (len(data) == 1 && int(data[0]) == rig.vfo) {
+			return ok
+		}
+
+	case civSplit:
+		if len(data) == 0 {
+			return []byte{cmd, 0x00} // Split can't be told from the display.
+		}
+
+	case civReadMeter:
+		if len(data) == 1 && data[0] == civSubSMeter {
+			// Icom reports S9 as 120 and S9+60dB as 241.
+			level := rig.sMeter * 120 / 9
+			return []byte{cmd, data[0], bcdByte(level / 100), bcdByte(level % 100)}
+		}
+
+	case civReadId:
+		if len(data) == 1 && data[0] == 0x00 {
+			return []byte{cmd, data[0], d.address}
+		}
+
+	case civTransmit:
+		if len(data) == 1 && data[0] == civSubTransmitting {
+			return []byte{cmd, data[0], bcdByte(boolInt(rig.transmitting))}
+		}
+		if len(data) == 2 && data[0] == civSubTransmitting && data[1] <= 1 {
+			backend.setPushToTalk(data[1] == 1)
+			return ok
+		}
+
+	case civVfoFrequency:
+		if len(data) == 1 && data[0] <= 1 {
+			hz := rig.frequencyA // data[0] is 0 for the selected VFO, 1 for the other.
+			if (rig.vfo == vfoB) != (data[0] == 1) {
+				hz = rig.frequencyB
+			}
+			return append([]byte{cmd, data[0]}, civFrequencyBcd(hz)...)
+		}
+	}
+	return ng
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func bcdByte(n int) byte {
+	return byte((n/10)<<4 | n%10)
+}
+
+// Icom sends frequencies as 5 BCD bytes, least significant first. E.g. 14.074 MHz is 00 40 07 14 00.
+func civFrequencyBcd(hz int64) []byte {
+	bcd := make([]byte, 5)
+	for i := range bcd {
+		bcd[i] = bcdByte(int(hz % 100))
+		hz /= 100
+	}
+	return bcd
+}
+
+func civBcdFrequency(bcd []byte) (hz int64, valid bool) {
+	if len(bcd) != 5 {
+		return 0, false
+	}
+	for i := len(bcd) - 1; i >= 0; i-- {
+		hi, lo := bcd[i]>>4, bcd[i]&0x0F
+		if hi > 9 || lo > 9 {
+			return 0, false
+		}
+		hz = hz*100 + int64(hi)*10 + int64(lo)
+	}
+	return hz, true
+}
@@ -0,0 +1,344 @@
+package controls
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Emulates the XML-RPC interface of flrig, so that programs which can control a radio through
+// flrig can control the uSDX directly. flrig's method set is documented at:
+//    http://www.w1hkj.com/flrig-help/xmlrpc_server.html
+
+// FlrigAddr is where flrig serves XML-RPC by default, and where its clients look.
+const FlrigAddr = "localhost:12345"
+
+const flrigVersion = "1.3.54" // The flrig release whose method set is emulated.
+
+const (
+	flrigFaultNoMethod = 1
+	flrigFaultBadParam = 2
+)
+
+// An XML-RPC value. Only scalar types are accepted as parameters.
+type xmlRpcValue struct {
+	String  *string `xml:"string"`
+	Int     *string `xml:"int"`
+	I4      *string `xml:"i4"`
+	Double  *string `xml:"double"`
+	Boolean *string `xml:"boolean"`
+	Text    string  `xml:",chardata"` // A value with no type element is a string.
+}
+
+func (v xmlRpcValue) asString() string {
+	for _, s := range []*string{v.String, v.Int, v.I4, v.Double, v.Boolean} {
+		if s != nil {
+			return strings.TrimSpace(*s)
+		}
+	}
+	return strings.TrimSpace(v.Text)
+}
+
+type xmlRpcCall struct {
+	MethodName string        `xml:"methodName"`
+	Params     []xmlRpcValue `xml:"params>param>value"`
+}
+
+type flrigFault struct {
+	code   int
+	reason string
+}
+
+// Flrig methods return the XML of a single <value>, or a fault.
+type flrigMethod func(params []string) (value string, fault *flrigFault)
+
+var flrigMethods map[string]flrigMethod
+
+func init() {
+	flrigMethods = map[string]flrigMethod{
+		"main.get_version":   flrigGetVersion,
+		"rig.get_xcvr":       flrigGetXcvr,
+		"rig.get_info":       flrigGetInfo,
+		"rig.get_AB":         flrigGetAB,
+		"rig.get_vfo":        flrigGetVfo,
+		"rig.get_vfoA":       flrigGetVfoA,
+		"rig.get_vfoB":       flrigGetVfoB,
+		"rig.set_vfo":        flrigSetFrequency,
+		"rig.set_frequency":  flrigSetFrequency,
+		"rig.set_vfoA":       flrigSetVfoA,
+		"rig.set_vfoB":       flrigSetVfoB,
+		"rig.get_mode":       flrigGetMode,
+		"rig.get_modeA":      flrigGetMode,
+		"rig.get_modeB":      flrigGetMode,
+		"rig.get_modes":      flrigGetModes,
+		"rig.set_mode":       flrigSetMode,
+		"rig.get_bw":         flrigGetBw,
+		"rig.get_bws":        flrigGetBws,
+		"rig.get_ptt":        flrigGetPtt,
+		"rig.set_ptt":        flrigSetPtt,
+		"rig.get_smeter":     flrigGetSMeter,
+		"rig.get_pwrmeter":   flrigGetZero,
+		"rig.get_swrmeter":   flrigGetZero,
+		"rig.get_split":      flrigGetZero,
+		"rig.get_power":      flrigGetPower,
+		"rig.get_sideband":   flrigGetSideband,
+		"system.listMethods": flrigListMethods,
+	}
+}
+
+// ProcessFlrig serves flrig's XML-RPC interface on addr, e.g. FlrigAddr. If addr can't be
+// listened on, e.g. because a real flrig has it, the error is logged and the app carries on
+// without it.
+func ProcessFlrig(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleFlrigRequest)
+	log.Printf("flrig server: %v", http.ListenAndServe(addr, mux))
+}
+
+func handleFlrigRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "XML-RPC requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var call xmlRpcCall
+	if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var params []string
+	for _, p := range call.Params {
+		params = append(params, p.asString())
+	}
+	log.Printf("FLRIG CMD %s %v", call.MethodName, params)
+
+	client := &catClient{name: r.RemoteAddr, dialect: flrigDialect{}}
+	cmd := []byte(strings.Join(append([]string{call.MethodName}, params...), " "))
+	rigMutex.Lock()
+	before := transcriptRigIfRecording()
+	response := executeFlrigCall(call.MethodName, params)
+	rigMutex.Unlock()
+	recordCatExchange(client, cmd, []byte(response), before)
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(response))
+}
+
+// flrigDialect lets flrig sessions be recorded in CAT transcripts, and replayed. Each command is
+// the method name followed by its parameters, separated by spaces, which none of the emulated
+// methods' parameters contain. The response is the XML-RPC response document.
+type flrigDialect struct{}
+
+func (flrigDialect) spec() string {
+	return "flrig"
+}
+
+func (flrigDialect) readCommand(r *bufio.Reader) ([]byte, error) {
+	return nil, fmt.Errorf("flrig calls are read by its HTTP server")
+}
+
+func (flrigDialect) describe(cmd []byte) string {
+	return string(cmd)
+}
+
+func (flrigDialect) undescribe(desc string) ([]byte, error) {
+	return []byte(desc), nil
+}
+
+func (flrigDialect) execute(client *catClient, cmd []byte) []byte {
+	fields := strings.Fields(string(cmd))
+	if len(fields) == 0 {
+		return []byte(xmlRpcFault(&flrigFault{flrigFaultNoMethod, "No method"}))
+	}
+	return []byte(executeFlrigCall(fields[0], fields[1:]))
+}
+
+// executeFlrigCall runs an flrig method and returns the complete XML-RPC response document.
+func executeFlrigCall(methodName string, params []string) string {
+	method, found := flrigMethods[methodName]
+	if !found {
+		return xmlRpcFault(&flrigFault{flrigFaultNoMethod, "No such method: " + methodName})
+	}
+	value, fault := method(params)
+	if fault != nil {
+		return xmlRpcFault(fault)
+	}
+	return xml.Header + "<methodResponse><params><param>" + value + "</param></params></methodResponse>"
+}
+
+func xmlRpcFault(fault *flrigFault) string {
+	log.Printf("FLRIG FAULT %d %s", fault.code, fault.reason)
+	return xml.Header + "<methodResponse><fault><value><struct>" +
+		"<member><name>faultCode</name>" + xmlRpcInt(fault.code) + "</member>" +
+		"<member><name>faultString</name>" + xmlRpcString(fault.reason) + "</member>" +
+		"</struct></value></fault></methodResponse>"
+}
+
+func xmlRpcString(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return "<value><string>" + sb.String() + "</string></value>"
+}
+
+func xmlRpcInt(i int) string {
+	return fmt.Sprintf("<value><i4>%d</i4></value>", i)
+}
+
+func xmlRpcDouble(f float64) string {
+	return fmt.Sprintf("<value><double>%f</double></value>", f)
+}
+
+func xmlRpcArray(values ...string) string {
+	return "<value><array><data>" + strings.Join(values, "") + "</data></array></value>"
+}
+
+func flrigFrequencyParam(params []string) (hz int64, fault *flrigFault) {
+	if len(params) < 1 {
+		return 0, &flrigFault{flrigFaultBadParam, "Missing frequency"}
+	}
+	f, err := strconv.ParseFloat(params[0], 64)
+	if err != nil || !frequencyInRange(int64(f)) {
+		return 0, &flrigFault{flrigFaultBadParam, "Bad frequency: " + params[0]}
+	}
+	return int64(f), nil
+}
+
+func flrigGetVersion(params []string) (string, *flrigFault) {
+	return xmlRpcString(flrigVersion), nil
+}
+
+func flrigGetXcvr(params []string) (string, *flrigFault) {
+	return xmlRpcString("uSDX"), nil
+}
+
+func flrigGetInfo(params []string) (string, *flrigFault) {
+	info := fmt.Sprintf("R:uSDX\nT:%s\nFA:%d\nM:%s\nL:%d\nU:\n", flrigAB(), rig.frequencyA, modeName(rig.mode), rigctlPassband())
+	return xmlRpcString(info), nil
+}
+
+func flrigAB() string {
+	if rig.vfo == vfoB {
+		return "B"
+	}
+	return "A"
+}
+
+func flrigGetAB(params []string) (string, *flrigFault) {
+	return xmlRpcString(flrigAB()), nil
+}
+
+func flrigGetVfo(params []string) (string, *flrigFault) {
+	return xmlRpcString(strconv.FormatInt(rig.displayedVfoFrequency(), 10)), nil
+}
+
+func flrigGetVfoA(params []string) (string, *flrigFault) {
+	return xmlRpcString(strconv.FormatInt(rig.frequencyA, 10)), nil
+}
+
+func flrigGetVfoB(params []string) (string, *flrigFault) {
+	return xmlRpcString(strconv.FormatInt(rig.frequencyB, 10)), nil
+}
+
+func flrigSetFrequency(params []string) (string, *flrigFault) {
+	hz, fault := flrigFrequencyParam(params)
+	if fault != nil {
+		return "", fault
+	}
+	backend.setFrequency(hz)
+	return xmlRpcDouble(float64(hz)), nil
+}
+
+// As with the FA and FB CAT commands, only the displayed VFO can be tuned.
+func flrigSetVfoA(params []string) (string, *flrigFault) {
+	if rig.vfo != vfoA {
+		return "", &flrigFault{flrigFaultBadParam, "VFO A is not displayed"}
+	}
+	return flrigSetFrequency(params)
+}
+
+func flrigSetVfoB(params []string) (string, *flrigFault) {
+	if rig.vfo != vfoB {
+		return "", &flrigFault{flrigFaultBadParam, "VFO B is not displayed"}
+	}
+	return flrigSetFrequency(params)
+}
+
+func flrigGetMode(params []string) (string, *flrigFault) {
+	return xmlRpcString(modeName(rig.mode)), nil
+}
+
+func flrigGetModes(params []string) (string, *flrigFault) {
+	return xmlRpcArray(xmlRpcString("LSB"), xmlRpcString("USB"), xmlRpcString("CW"), xmlRpcString("FM"), xmlRpcString("AM")), nil
+}
+
+func flrigSetMode(params []string) (string, *flrigFault) {
+	if len(params) < 1 {
+		return "", &flrigFault{flrigFaultBadParam, "Missing mode"}
+	}
+	mode, found := modeNamed(params[0])
+	if !found {
+		return "", &flrigFault{flrigFaultBadParam, "Unknown mode: " + params[0]}
+	}
+	backend.setMode(mode)
+	return xmlRpcInt(0), nil
+}
+
+func flrigGetSideband(params []string) (string, *flrigFault) {
+	if rig.mode == modeLsb {
+		return xmlRpcString("L"), nil
+	}
+	return xmlRpcString("U"), nil
+}
+
+// The bandwidth is the Filter BW setting, as rigctld reports it, and the only one offered.
+func flrigGetBw(params []string) (string, *flrigFault) {
+	return xmlRpcArray(xmlRpcString(strconv.Itoa(rigctlPassband())), xmlRpcString("")), nil
+}
+
+func flrigGetBws(params []string) (string, *flrigFault) {
+	return xmlRpcArray(xmlRpcArray(xmlRpcString("Bandwidth"), xmlRpcString(strconv.Itoa(rigctlPassband())))), nil
+}
+
+func flrigGetPtt(params []string) (string, *flrigFault) {
+	if rig.transmitting {
+		return xmlRpcInt(1), nil
+	}
+	return xmlRpcInt(0), nil
+}
+
+func flrigSetPtt(params []string) (string, *flrigFault) {
+	if len(params) < 1 || (params[0] != "0" && params[0] != "1") {
+		return "", &flrigFault{flrigFaultBadParam, "PTT must be 0 or 1"}
+	}
+	backend.setPushToTalk(params[0] == "1")
+	return xmlRpcInt(0), nil
+}
+
+// flrig scales its s-meter from 0 to 100, with S9 at 50.
+func flrigGetSMeter(params []string) (string, *flrigFault) {
+	return xmlRpcString(strconv.Itoa(rig.sMeter * 50 / 9)), nil
+}
+
+func flrigGetPower(params []string) (string, *flrigFault) {
+	return xmlRpcInt(5), nil // Matches the PC command's stand-in.
+}
+
+func flrigGetZero(params []string) (string, *flrigFault) {
+	return xmlRpcInt(0), nil
+}
+
+func flrigListMethods(params []string) (string, *flrigFault) {
+	var names []string
+	for name := range flrigMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var values []string
+	for _, name := range names {
+		values = append(values, xmlRpcString(name))
+	}
+	return xmlRpcArray(values...), nil
+}
@@ -0,0 +1,24 @@
+package controls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlrigBandwidthIsRigctldPassband(t *testing.T) {
+	savedItems := menuItems
+	defer func() { menuItems = savedItems }()
+	menuItems = []MenuItem{{Name: "Filter BW", Value: "500", EditType: editChoice}}
+
+	bw, _ := flrigGetBw(nil)
+	bws, _ := flrigGetBws(nil)
+	info, _ := flrigGetInfo(nil)
+	for _, got := range []string{bw, bws} {
+		if !strings.Contains(got, "<string>500</string>") {
+			t.Errorf("got %q, want a bandwidth of 500", got)
+		}
+	}
+	if !strings.Contains(info, "L:500&#xA;") { // XML escapes the newlines.
+		t.Errorf("rig.get_info got %q, want L:500", info)
+	}
+}
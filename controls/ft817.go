@@ -0,0 +1,174 @@
+package controls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Emulates the CAT interface of a Yaesu FT-817/818. Every command is 5 bytes long: 4 parameter
+// bytes followed by an opcode. The protocol is documented in the FT-817ND operating manual, and
+// in detail at:
+//    http://www.ka7oei.com/ft817_meow.html
+
+const ft817CommandLen = 5
+
+// FT-817 opcodes
+const (
+	ft817LockOn        = 0x00
+	ft817SetFrequency  = 0x01
+	ft817SplitOn       = 0x02
+	ft817ReadFreqMode  = 0x03
+	ft817SetMode       = 0x07
+	ft817PttOn         = 0x08
+	ft817LockOff       = 0x80
+	ft817ToggleVfo     = 0x81
+	ft817SplitOff      = 0x82
+	ft817PttOff        = 0x88
+	ft817ReadEeprom    = 0xBB
+	ft817WriteEeprom   = 0xBC
+	ft817ReadTxMeters  = 0xBD
+	ft817ReadRxStatus  = 0xE7
+	ft817ReadTxStatus  = 0xF7
+	ft817PowerOn       = 0x0F
+	ft817PowerOff      = 0x8F
+	ft817AlreadyInThat = 0xF0 // Answer to an on/off command when the rig is already in that state
+)
+
+// Yaesu mode numbers, keyed by the Kenwood mode numbers used by rigState.
+var ft817Modes = map[int]byte{
+	modeLsb: 0x00,
+	modeUsb: 0x01,
+	modeCw:  0x02,
+	modeAm:  0x04,
+	modeFm:  0x08,
+}
+
+type ft817Dialect struct{}
+
+var ft817Locked = false // Stand-in, since the uSDX has no dial lock.
+
+func (ft817Dialect) spec() string {
+	return "ft817"
+}
+
+func (ft817Dialect) readCommand(r *bufio.Reader) ([]byte, error) {
+	cmd := make([]byte, ft817CommandLen)
+	_, err := io.ReadFull(r, cmd)
+	return cmd, err
+}
+
+func (ft817Dialect) describe(cmd []byte) string {
+	return fmt.Sprintf("% X", cmd)
+}
+
+func (ft817Dialect) undescribe(desc string) ([]byte, error) {
+	return undescribeHex(desc)
+}
+
+func (ft817Dialect) execute(client *catClient, cmd []byte) []byte {
+	p1, opcode := cmd[0], cmd[4]
+
+	switch opcode {
+
+	case ft817SetFrequency:
+		if hz, valid := ft817BcdFrequency(cmd[0:4]); valid && frequencyInRange(hz) {
+			backend.setFrequency(hz)
+		}
+		return nil
+
+	case ft817ReadFreqMode:
+		return append(ft817FrequencyBcd(rig.displayedVfoFrequency()), ft817Modes[rig.mode])
+
+	case ft817SetMode:
+		for mode, yaesuMode := range ft817Modes {
+			if yaesuMode == p1 {
+				backend.setMode(mode)
+			}
+		}
+		return nil
+
+	case ft817PttOn:
+		wasOn := rig.transmitting
+		backend.setPushToTalk(true)
+		return ft817OnOffAnswer(wasOn)
+
+	case ft817PttOff:
+		wasOff := !rig.transmitting
+		backend.setPushToTalk(false)
+		return ft817OnOffAnswer(wasOff)
+
+	case ft817LockOn:
+		wasOn := ft817Locked
+		ft817Locked = true
+		return ft817OnOffAnswer(wasOn)
+
+	case ft817LockOff:
+		wasOff := !ft817Locked
+		ft817Locked = false
+		return ft817OnOffAnswer(wasOff)
+
+	// Split can't be switched from the controller board, or told from the display, so these
+	// report it as off.
+	case ft817SplitOn:
+		return ft817OnOffAnswer(false)
+
+	case ft817SplitOff:
+		return ft817OnOffAnswer(true)
+
+	case ft817ReadRxStatus:
+		// Bits 0-3: S-meter, where 9 is S9 and 10-15 are S9+10dB to S9+60dB.
+		// Bit 7: 0 if the squelch is open. The uSDX has no squelch.
+		return []byte{byte(rig.sMeter & 0x0F)}
+
+	case ft817ReadTxStatus:
+		// Bit 7: 0 if PTT is on. Bit 5: 0 if split is on. Bits 0-3: power meter, which the uSDX doesn't show.
+		var status byte
+		if !rig.transmitting {
+			status |= 0x80
+		}
+		status |= 0x20
+		return []byte{status}
+
+	case ft817ReadEeprom:
+		return []byte{0x00, 0x00} // The uSDX has no FT-817 EEPROM to read.
+
+	case ft817ReadTxMeters:
+		return []byte{0x00, 0x00}
+
+	case ft817ToggleVfo, ft817WriteEeprom, ft817PowerOn, ft817PowerOff:
+		return nil
+	}
+
+	return nil // The FT-817 silently ignores unknown commands.
+}
+
+func ft817OnOffAnswer(alreadyInThatState bool) []byte {
+	if alreadyInThatState {
+		return []byte{ft817AlreadyInThat}
+	}
+	return []byte{0x00}
+}
+
+// Yaesu sends frequencies as 4 BCD bytes in units of 10 Hz, most significant first.
+// E.g. 14.074 MHz is 01 40 74 00.
+func ft817FrequencyBcd(hz int64) []byte {
+	daHz := hz / 10
+	bcd := make([]byte, 4)
+	for i := len(bcd) - 1; i >= 0; i-- {
+		bcd[i] = bcdByte(int(daHz % 100))
+		daHz /= 100
+	}
+	return bcd
+}
+
+func ft817BcdFrequency(bcd []byte) (hz int64, valid bool) {
+	for _, b := range bcd {
+		hi, lo := b>>4, b&0x0F
+		if hi > 9 || lo > 9 {
+			return 0, false
+		}
+		hz = hz*100 + int64(hi)*10 + int64(lo)
+	}
+	return hz * 10, true
+}
@@ -1,159 +1,318 @@
 package controls
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
+	"log"
 	"strconv"
-	"strings"
 	"time"
 	"uSDX/ambEmuLcd"
+	"uSDX/usdxScreen"
 )
 
 var line1 []byte
 var line2 []byte
 var cursor image.Point
+var screen usdxScreen.Screen = usdxScreen.Blank{} // What line1, line2 and cursor show.
 
-var currentFrequencyA int64 = 0 // Must be MMkkkHHH format, with leading zeros.
+var settledEvents chan *ambEmuLcd.Settled = nil // Guarded by rigMutex.
 
-var settledEvents chan *ambEmuLcd.Settled = nil
+// radioReady is closed while the radio is on and has got past its splash screen to the main
+// screen. The high level controls wait for it, so that they pause while the radio is off.
+var radioReady = make(chan bool)
 
-var uSdxSettingNames []string // The settings on the menu, in order.
-
-func InitHighLevelControls() {
-	//uSdxSettingNames = nil
-	//asyncGatherUsdxSettings()
+func init() {
+	close(radioReady)
 }
 
-func indexOfSettingName(name string) (found bool, index int) {
-	for i, v := range uSdxSettingNames {
-		if v == name {
-			return true, i
-		}
+// HandlePowerEvent records the radio being switched on or off, as reported by the controller board.
+func HandlePowerEvent(on bool) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	if !on && isRadioReady() {
+		radioReady = make(chan bool)
 	}
-	return false, 999
+	rig.poweredOn = on
 }
 
-func menuLineToTrimmedString(line []byte) string {
-	sLine1 := strings.TrimLeft(string(line), " 0123456789.")
-	return strings.TrimSpace(sLine1)
+// RadioPoweredOn reports whether the radio is switched on.
+func RadioPoweredOn() bool {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	return rig.poweredOn
 }
 
-func asyncGatherUsdxSettings() {
-	go func() {
+// Callers must hold rigMutex.
+func isRadioReady() bool {
+	select {
+	case <-radioReady:
+		return true
+	default:
+		return false
+	}
+}
 
-		time.Sleep(time.Second)
-		settledEvents = make(chan *ambEmuLcd.Settled, 100)
+// waitForRadio blocks while the radio is off or starting up.
+func waitForRadio(ctx context.Context) error {
+	rigMutex.Lock()
+	ready := radioReady
+	rigMutex.Unlock()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-		lastLine1Seen := ""
-		ClickLeftButton()
-		for {
-			e := <-settledEvents
-			sLine1 := menuLineToTrimmedString(e.Line1Data)
-			if sLine1 == lastLine1Seen {
-				break
-			}
-			uSdxSettingNames = append(uSdxSettingNames, sLine1)
-			RotateEncoderClockwise()
-			lastLine1Seen = sLine1
-		}
-		settledEvents = nil
-		ClickRightButton()
-		fmt.Printf("%v\n", uSdxSettingNames)
-	}()
+// InitHighLevelControls loads the menu cache at menuCache, which may be empty for none.
+func InitHighLevelControls(menuCache string) {
+	loadMenuCache(menuCache)
 }
 
 func HandleSettledEvent(e *ambEmuLcd.Settled) {
+	rigMutex.Lock()
 	line1 = e.Line1Data
 	line2 = e.Line2Data
 	cursor = e.CursorPos
+	screen = usdxScreen.ParseSettled(e)
 
-	if line2[0] == 6 { // Custom character 6 is used to indicate VFO A
-		hzStr := string(line2[1:11]) + "0" // uSDX doesn't display "ones" position, so suffix a "0"
-		hzStr = strings.ReplaceAll(hzStr, ",", "")
-		hzStr = strings.ReplaceAll(hzStr, " ", "")
-		currentFrequencyA, _ = strconv.ParseInt(hzStr, 10, 64)
+	if main, ok := screen.(usdxScreen.Main); ok {
+		rig.updateFromMainScreen(main)
+		if rig.poweredOn && !isRadioReady() {
+			close(radioReady)
+		}
 	}
+	ready := isRadioReady()
+	events := settledEvents
+	autoInfo := autoInfoMessages()
+	subscribers := autoInfoSubscribers()
+	rigMutex.Unlock()
 
-	if settledEvents != nil {
-		settledEvents <- e
+	pushAutoInfo(subscribers, autoInfo)
+
+	if splash, ok := screen.(usdxScreen.Splash); ok {
+		noteFirmware(splash)
+	}
+
+	if events != nil && ready {
+		events <- e
 	}
 }
 
-func asyncSetParameter(nameToSet, val string) {
+// Each high level operation is a sequence of button clicks and encoder turns, each of which is
+// confirmed by the Settled event that follows it. The operations take a context, with which
+// they can be cancelled between steps, and they return an error if the radio doesn't respond
+// to a step within stepTimeout. Whenever an operation ends, it leaves the radio on its main
+// screen. The operations are run one at a time by the scheduler. The functions that don't take
+// a context queue the operation in the background, logging any error.
+
+// stepTimeout is how long to wait for the display to settle after a button or the encoder is
+// operated.
+const stepTimeout = time.Second
+
+// recoveryTimeout is how long an aborted operation has to get back to the main screen.
+const recoveryTimeout = 10 * time.Second
+
+// ErrNoResponse is returned when the display doesn't change after a step of an operation.
+var ErrNoResponse = errors.New("the radio didn't respond")
+
+// runSequence waits for the radio, then runs seq with Settled events arriving on
+// settledEvents, and finally returns the radio to the main screen, even if seq was aborted.
+// Only the scheduler runs sequences, so that they don't share settledEvents.
+func runSequence(ctx context.Context, seq func(ctx context.Context) error) error {
+	if err := waitForRadio(ctx); err != nil {
+		return err
+	}
+	rigMutex.Lock()
 	settledEvents = make(chan *ambEmuLcd.Settled, 100)
-	go func() {
+	rigMutex.Unlock()
+	defer func() {
+		rigMutex.Lock()
+		settledEvents = nil
+		rigMutex.Unlock()
+	}()
 
-		// Enter menu and find out where we are in it
-		ClickLeftButton()
-		e := <-settledEvents
-		currentSettingName := menuLineToTrimmedString(e.Line1Data)
+	err := seq(ctx)
 
-		// Calculate how far we need to move, and in wich direction
-		found1, currentIndex := indexOfSettingName(currentSettingName)
-		found2, targetIndex := indexOfSettingName(nameToSet)
-		if !found1 || !found2 {
-			ClickRightButton()
-			return
-		}
-		diff := targetIndex - currentIndex
-		dir := 1
-		if diff < 0 {
-			dir = -1
-			diff *= -1
+	recoveryCtx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+	if backErr := returnToMainScreen(recoveryCtx); backErr != nil {
+		if err == nil {
+			return backErr
 		}
+		return fmt.Errorf("%v, and %v", err, backErr)
+	}
+	return err
+}
 
-		// Move to the target setting
-		for n := 0; n < diff; n++ {
-			RotateEncoder(dir)
-			//<-settledEvents
-			time.Sleep(50 * time.Millisecond)
-		}
+// nextScreen waits for the next Settled event on settledEvents, and returns its screen.
+func nextScreen(ctx context.Context) (usdxScreen.Screen, error) {
+	select {
+	case e := <-settledEvents:
+		return usdxScreen.ParseSettled(e), nil
+	case <-time.After(stepTimeout):
+		return nil, ErrNoResponse
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
-		// Edit the target setting's value
-		ClickLeftButton()
+// awaitScreen is like nextScreen, but for a step that may not change the display, e.g. turning
+// the encoder at the end of the menu. If there's no Settled event, it returns the current screen.
+func awaitScreen(ctx context.Context) (usdxScreen.Screen, error) {
+	s, err := nextScreen(ctx)
+	if err == ErrNoResponse {
+		return currentScreen(), nil
+	}
+	return s, err
+}
 
-		settledEvents = nil
-		ClickRightButton()
+func currentScreen() usdxScreen.Screen {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	return screen
+}
+
+// currentMainScreen returns the main screen, with its second line and the cursor, or an error if
+// another screen is shown.
+func currentMainScreen() (main usdxScreen.Main, mainLine2 []byte, cursorPos image.Point, err error) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	main, ok := screen.(usdxScreen.Main)
+	if !ok {
+		return main, nil, cursor, fmt.Errorf("main screen isn't displayed")
+	}
+	return main, line2, cursor, nil
+}
+
+// mainScreenTimeout is how long to wait for the main screen, e.g. for the radio to start up.
+const mainScreenTimeout = 10 * time.Second
+
+// waitForMainScreen waits for the main screen, then discards the Settled events that led up
+// to it, so that the next step sees only the events that follow it.
+func waitForMainScreen(ctx context.Context) error {
+	deadline := time.Now().Add(mainScreenTimeout)
+	for {
+		if _, isMain := currentScreen().(usdxScreen.Main); isMain {
+			for len(settledEvents) > 0 {
+				<-settledEvents
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("main screen isn't displayed")
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// returnToMainScreen clicks the right button until the main screen is shown. Each click goes
+// one step back, from editing a setting to the menu, and from the menu to the main screen.
+// It only clicks on the menu screens, as a click on the main screen changes the mode.
+func returnToMainScreen(ctx context.Context) error {
+	for clicks := 0; ; clicks++ {
+		switch currentScreen().(type) {
+		case usdxScreen.Main:
+			return nil
+		case usdxScreen.MenuList, usdxScreen.MenuEdit:
+		default:
+			return fmt.Errorf("neither the menu nor the main screen is displayed")
+		}
+		if clicks == 3 {
+			return fmt.Errorf("didn't get back to the main screen")
+		}
 		ClickRightButton()
-	}()
+		if _, err := awaitScreen(ctx); err != nil {
+			return err
+		}
+	}
 }
 
-func SkipToFreqDigit(sought int) {
+// inBackground logs the error, if any, from an operation that was queued in the background.
+func inBackground(desc string, result <-chan error) {
 	go func() {
-		delta := sought - cursor.X
+		if err := <-result; err != nil {
+			log.Printf("%s: %v", desc, err)
+		}
+	}()
+}
+
+// SkipToFreqDigit moves the cursor to the frequency digit at col, in the background.
+func SkipToFreqDigit(col int) {
+	inBackground("Skip to digit", queueSkipToFreqDigit(context.Background(), col))
+}
+
+// SkipToFreqDigitContext moves the cursor to the frequency digit at col, by clicking the encoder.
+func SkipToFreqDigitContext(ctx context.Context, col int) error {
+	return await(ctx, queueSkipToFreqDigit(ctx, col))
+}
+
+func queueSkipToFreqDigit(ctx context.Context, col int) <-chan error {
+	return enqueue(ctx, "Skip to digit", kindCursor, func(ctx context.Context) error {
+		_, _, cursorPos, err := currentMainScreen()
+		if err != nil {
+			return err
+		}
+		delta := col - cursorPos.X
 		if delta < 0 {
 			delta = 9 + delta // "9" is the number of digits/commas in the frequency display
 		}
 		for i := 0; i < delta; i++ {
 			ClickEncoderButton()
-			time.Sleep(50 * time.Millisecond)
+			if _, err := nextScreen(ctx); err != nil {
+				return err
+			}
 		}
-	}()
+		return nil
+	})
 }
 
 var digitPositionMap = []byte{99, 0, 1, 99, 2, 3, 4, 99, 5, 6}
-var mostRecentHzStr = ""
 
 const uSdrFreqChars = 9 // including leading spaces and commas
 
+// SetFrequency tunes the VFO to hzStr, an 11 digit frequency in Hz, in the background.
+// A burst of sets is merged by the scheduler, which only runs the last.
 func SetFrequency(hzStr string) {
-	settledEvents = make(chan *ambEmuLcd.Settled, 100)
-	go func() {
-		// Setting frequency is idempotent.
-		if hzStr == mostRecentHzStr {
-			return
-		}
-		mostRecentHzStr = hzStr
+	hz, _ := strconv.ParseInt(hzStr, 10, 64)
+	inBackground("Set frequency", queueSetFrequency(context.Background(), hz))
+}
 
-		hzStr = strings.TrimLeft(hzStr, "0")
-		daHzStr := hzStr[0 : len(hzStr)-1] // deca-hertz string
-		daHzStr = fmt.Sprintf("%07s", daHzStr)
-		//fmt.Println(daHzStr)
+// SetFrequencyContext tunes the VFO to hz, which the uSDX shows to the nearest 10 Hz below.
+// It goes through the frequency's digits, from the cursor's, turning the encoder on each to
+// reach its target, until the cursor is back where it started.
+func SetFrequencyContext(ctx context.Context, hz int64) error {
+	return await(ctx, queueSetFrequency(ctx, hz))
+}
 
-		startX := cursor.X
+func queueSetFrequency(ctx context.Context, hz int64) <-chan error {
+	if !frequencyInRange(hz) {
+		return failed(fmt.Errorf("%d Hz is out of range", hz))
+	}
+	daHzStr := fmt.Sprintf("%07d", hz/10) // deca-hertz string
+	return enqueue(ctx, "Set frequency", kindFrequency, func(ctx context.Context) error {
+		_, _, cursorPos, err := currentMainScreen()
+		if err != nil {
+			return err
+		}
+		startX := cursorPos.X
+		if cursorPos.Y != 2 || startX < 1 || startX > uSdrFreqChars {
+			return fmt.Errorf("cursor isn't on the frequency")
+		}
 		for i := startX; i < startX+uSdrFreqChars; i++ { // cycle through the display's frequency characters
 			iMod := ((i - 1) % uSdrFreqChars) + 1
-			currChar := line2[iMod]
+			_, mainLine2, _, err := currentMainScreen()
+			if err != nil {
+				return err
+			}
+			currChar := mainLine2[iMod]
 			if currChar != ',' {
 				var currDigit byte
 				if currChar == ' ' { // The display shows leading zeros as spaces.
@@ -171,26 +330,88 @@ func SetFrequency(hzStr string) {
 				//log.Printf("index:%d curr:%d targ:%d delta:%d", iMod, currDigit, targetDigit, delta)
 				for n := 0; n < delta; n++ {
 					RotateEncoder(dir)
-					<-settledEvents
+					if _, err := nextScreen(ctx); err != nil {
+						return err
+					}
 				}
 			}
 			ClickEncoderButton()
-			<-settledEvents
+			if _, err := nextScreen(ctx); err != nil {
+				return err
+			}
 		}
-		settledEvents = nil
-	}()
+		return nil
+	})
+}
+
+// The right button steps through the modes in the same order as the Kenwood mode numbers,
+// wrapping from AM back to LSB.
+const modeCount = 5
+
+// SetMode selects the mode, numbered as in the Kenwood MD command, in the background.
+func SetMode(mode int) {
+	inBackground("Set mode", queueSetMode(context.Background(), mode))
+}
+
+// SetModeContext selects the mode, numbered as in the Kenwood MD command, by clicking the right
+// button on the main screen. Each click is confirmed by the Settled event that follows it.
+func SetModeContext(ctx context.Context, mode int) error {
+	return await(ctx, queueSetMode(ctx, mode))
+}
+
+func queueSetMode(ctx context.Context, mode int) <-chan error {
+	return enqueue(ctx, "Set mode", kindMode, func(ctx context.Context) error {
+		for i := 0; i < modeCount; i++ {
+			main, _, _, err := currentMainScreen()
+			if err != nil {
+				return err
+			}
+			currMode, found := modeNamed(main.Mode)
+			if !found {
+				return fmt.Errorf("mode isn't displayed")
+			}
+			if currMode == mode {
+				return nil
+			}
+			ClickRightButton()
+			if _, err := nextScreen(ctx); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("mode %d wasn't reached", mode)
+	})
 }
 
 // ForceRefresh is used at app startup to force the uSDX to "redraw" the main/start "screen".
+// Then it discovers the menu, unless it's cached.
 func ForceRefresh() {
 	time.Sleep(500 * time.Millisecond)
-	settledEvents = make(chan *ambEmuLcd.Settled, 100)
+	refreshed := enqueue(context.Background(), "Refresh", kindRefresh, refreshScreen)
 	go func() {
-		ClickLeftButton()
-		<-settledEvents
-		time.Sleep(500 * time.Millisecond)
-		ClickRightButton()
-		<-settledEvents
-		settledEvents = nil
+		err := <-refreshed
+		if err == nil {
+			err = ensureMenuItems(context.Background())
+		}
+		if err != nil {
+			log.Printf("Refresh: %v", err)
+		}
 	}()
 }
+
+// RefreshScreen makes the uSDX redraw its main screen, by going into the menu and back out.
+func RefreshScreen(ctx context.Context) error {
+	return schedule(ctx, "Refresh", kindRefresh, refreshScreen)
+}
+
+func refreshScreen(ctx context.Context) error {
+	ClickLeftButton()
+	if _, err := awaitScreen(ctx); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(500 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
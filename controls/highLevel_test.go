@@ -0,0 +1,47 @@
+package controls
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"uSDX/usdxScreen"
+)
+
+func TestReturnToMainScreenOnlyClicksInTheMenu(t *testing.T) {
+	var clicks bytes.Buffer
+	rigMutex.Lock()
+	savedPort, savedScreen := port, screen
+	rigMutex.Unlock()
+	defer func() {
+		rigMutex.Lock()
+		port, screen = savedPort, savedScreen
+		rigMutex.Unlock()
+	}()
+	port = &clicks
+
+	// The context is done, so that the test needn't wait for the screen to change.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		screen usdxScreen.Screen
+		clicks int
+	}{
+		{usdxScreen.Main{}, 0}, // A click would change the mode.
+		{usdxScreen.Blank{}, 0},
+		{usdxScreen.Splash{}, 0},
+		{usdxScreen.MenuList{}, 1},
+		{usdxScreen.MenuEdit{}, 1},
+	} {
+		clicks.Reset()
+		rigMutex.Lock()
+		screen = test.screen
+		rigMutex.Unlock()
+		returnToMainScreen(ctx)
+		if clicks.Len() != test.clicks {
+			t.Errorf("%T: %d clicks, want %d", test.screen, clicks.Len(), test.clicks)
+		}
+		if bytes.Count(clicks.Bytes(), []byte{clickRightButton}) != clicks.Len() {
+			t.Errorf("%T: clicked %v, want only the right button", test.screen, clicks.Bytes())
+		}
+	}
+}
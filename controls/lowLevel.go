@@ -1,6 +1,6 @@
 package controls
 
-import "github.com/tarm/serial"
+import "io"
 
 // These MUST be the values defined by the uSDX controller board:
 const (
@@ -24,7 +24,7 @@ var controlNames = []string{
 	"End Push to Talk",
 }
 
-var port *serial.Port
+var port io.Writer // The controller board, or a stand-in for it.
 
 func hardwareAction(action byte) {
 	//fmt.Printf("%s\n", controlNames[action])
@@ -34,12 +34,20 @@ func hardwareAction(action byte) {
 	}
 }
 
-func InitLowLevelControls(p *serial.Port) {
+func InitLowLevelControls(p io.Writer) {
 	port = p
 }
 
-func StartPushToTalk()               { hardwareAction(startPushToTalk) }
-func EndPushToTalk()                 { hardwareAction(endPushToTalk) }
+func StartPushToTalk() {
+	hardwareAction(startPushToTalk)
+	setTransmitting(true)
+}
+
+func EndPushToTalk() {
+	hardwareAction(endPushToTalk)
+	setTransmitting(false)
+}
+
 func ClickLeftButton()               { hardwareAction(clickLeftButton) }
 func ClickRightButton()              { hardwareAction(clickRightButton) }
 func ClickEncoderButton()            { hardwareAction(clickEncoderButton) }
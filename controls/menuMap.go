@@ -0,0 +1,341 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"uSDX/usdxScreen"
+)
+
+// The app discovers the uSDX's menu by walking it, and keeps what it finds in a cache file, by
+// firmware, so that it only walks the menu again when the firmware changes. The firmware is
+// known from the splash screen, which is only shown when the radio is switched on, so the
+// cache also records the last firmware seen, for when the app starts with the radio already on.
+
+// A MenuItem is one item on the uSDX's menu.
+type MenuItem struct {
+	Index    int    `json:"index"`    // From 0 at the top of the menu.
+	Number   string `json:"number"`   // As shown before the name, e.g. "1.2"
+	Name     string `json:"name"`     // E.g. "Mode"
+	Value    string `json:"value"`    // As shown when the menu was walked.
+	EditType string `json:"editType"` // editNumber or editChoice
+}
+
+// Edit types, found when the menu is walked. The encoder steps a number by one, and a choice on
+// to the next choice.
+const (
+	editNumber = "number"
+	editChoice = "choice"
+)
+
+// A menuCache is the contents of a menu cache file.
+type menuCache struct {
+	LastFirmware string                `json:"lastFirmware"`
+	Menus        map[string][]MenuItem `json:"menus"` // By firmware fingerprint
+}
+
+// unknownFirmware is the fingerprint used until a splash screen has been seen.
+const unknownFirmware = "unknown"
+
+var (
+	menuMutex     sync.Mutex // Guards the variables below.
+	menuCachePath string     // Empty if the menu isn't cached.
+	menuFirmware  = unknownFirmware
+	menuItems     []MenuItem // The menu of menuFirmware, or nil if it hasn't been walked.
+	menuCacheData = menuCache{Menus: map[string][]MenuItem{}}
+	menuCacheGen  int // Counts changes to menuCacheData, so that a stale one is never written.
+)
+
+var (
+	menuCacheFileMutex sync.Mutex // Serializes writes of the cache file, and guards menuCacheSaved.
+	menuCacheSaved     int        // The menuCacheGen last written.
+)
+
+var menuWalkMutex sync.Mutex // Serializes walks of the menu.
+
+// The menu is expected to be shorter than this, which stops a walk that never ends.
+const maxMenuItems = 100
+
+// MenuItems returns the items on the uSDX's menu, or nil if it hasn't been discovered yet.
+func MenuItems() []MenuItem {
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	return append([]MenuItem(nil), menuItems...)
+}
+
+// MenuFirmware returns the fingerprint of the firmware that MenuItems belong to.
+func MenuFirmware() string {
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	return menuFirmware
+}
+
+// loadMenuCache loads the menu of the last firmware seen from the cache at path, if any.
+func loadMenuCache(path string) {
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	menuCachePath = path
+	if path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &menuCacheData)
+	}
+	if err != nil {
+		log.Printf("Menu cache %s: %v", path, err)
+		return
+	}
+	if menuCacheData.Menus == nil {
+		menuCacheData.Menus = map[string][]MenuItem{}
+	}
+	if menuCacheData.LastFirmware != "" {
+		menuFirmware = menuCacheData.LastFirmware
+	}
+	menuItems = menuCacheData.Menus[menuFirmware]
+}
+
+// Callers must hold menuMutex.
+func saveMenuCache() {
+	menuCacheWriter()()
+}
+
+// menuCacheWriter returns a function that writes menuCacheData, as it is now, to the cache file.
+// It can be run once menuMutex is released, e.g. so that the LCD events aren't held up by the
+// file system, and does nothing if a later version has been written already. Callers must hold
+// menuMutex.
+func menuCacheWriter() func() {
+	if menuCachePath == "" {
+		return func() {}
+	}
+	path := menuCachePath
+	menuCacheGen++
+	gen := menuCacheGen
+	data, err := json.MarshalIndent(&menuCacheData, "", "  ")
+	return func() {
+		menuCacheFileMutex.Lock()
+		defer menuCacheFileMutex.Unlock()
+		if gen < menuCacheSaved {
+			return
+		}
+		if err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			log.Printf("Menu cache %s: %v", path, err)
+			return
+		}
+		menuCacheSaved = gen
+	}
+}
+
+// noteFirmware records the firmware shown on a splash screen, and discovers its menu once the
+// radio has started, unless it's cached. It's called as the LCD events are handled, so the cache
+// file is written on another goroutine.
+func noteFirmware(splash usdxScreen.Splash) {
+	fingerprint := splash.Product + " " + splash.Version
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	if fingerprint == menuFirmware && menuItems != nil {
+		return
+	}
+	menuFirmware = fingerprint
+	menuItems = menuCacheData.Menus[fingerprint]
+	menuCacheData.LastFirmware = fingerprint
+	go menuCacheWriter()()
+	if menuItems == nil {
+		go func() {
+			if err := ensureMenuItems(context.Background()); err != nil {
+				log.Printf("Menu discovery: %v", err)
+			}
+		}()
+	}
+}
+
+// ensureMenuItems walks the menu, from the main screen, unless its items are already known.
+func ensureMenuItems(ctx context.Context) error {
+	menuWalkMutex.Lock()
+	defer menuWalkMutex.Unlock()
+	if MenuItems() != nil {
+		return nil
+	}
+	_, err := discoverMenu(ctx)
+	return err
+}
+
+// DiscoverMenu walks the uSDX's menu, from the main screen, records its items for the current
+// firmware, and returns them. It leaves the radio on its main screen.
+func DiscoverMenu(ctx context.Context) ([]MenuItem, error) {
+	menuWalkMutex.Lock()
+	defer menuWalkMutex.Unlock()
+	return discoverMenu(ctx)
+}
+
+// Callers must hold menuWalkMutex.
+func discoverMenu(ctx context.Context) ([]MenuItem, error) {
+	var items []MenuItem
+	err := schedule(ctx, "Discover menu", kindMenu, func(ctx context.Context) error {
+		if err := waitForMainScreen(ctx); err != nil {
+			return err
+		}
+		var err error
+		items, err = walkMenu(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	menuItems = items
+	menuCacheData.Menus[menuFirmware] = items
+	menuCacheData.LastFirmware = menuFirmware
+	saveMenuCache()
+	log.Printf("Discovered %d menu items of %s firmware", len(items), menuFirmware)
+	return append([]MenuItem(nil), items...), nil
+}
+
+// walkMenu enters the menu, goes to the top, then records each item down to the bottom.
+func walkMenu(ctx context.Context) ([]MenuItem, error) {
+	ClickLeftButton()
+	item, err := awaitMenuList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := 0; ; n++ {
+		if n == maxMenuItems {
+			return nil, fmt.Errorf("top of menu not found")
+		}
+		RotateEncoderCounterclockwise()
+		prev, err := awaitMenuList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if prev.Number == item.Number {
+			break
+		}
+		item = prev
+	}
+
+	var items []MenuItem
+	for {
+		editType, err := editTypeOf(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, MenuItem{
+			Index:    len(items),
+			Number:   item.Number,
+			Name:     item.Name,
+			Value:    item.Value,
+			EditType: editType,
+		})
+		if len(items) == maxMenuItems {
+			return nil, fmt.Errorf("bottom of menu not found")
+		}
+		RotateEncoderClockwise()
+		next, err := awaitMenuList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if next.Number == item.Number {
+			return items, nil
+		}
+		item = next
+	}
+}
+
+// awaitMenuList is awaitScreen, for a step that should leave the menu showing.
+func awaitMenuList(ctx context.Context) (usdxScreen.MenuList, error) {
+	s, err := awaitScreen(ctx)
+	if err != nil {
+		return usdxScreen.MenuList{}, err
+	}
+	item, ok := s.(usdxScreen.MenuList)
+	if !ok {
+		return item, fmt.Errorf("menu isn't displayed")
+	}
+	return item, nil
+}
+
+// editTypeOf finds the edit type of the item that the menu shows. A value that isn't a number is
+// a choice, but some choices look like numbers, e.g. Filter BW's "2400", so those are edited to
+// see whether the encoder steps them by one. The edit is undone, and the menu left on the item.
+func editTypeOf(ctx context.Context, item usdxScreen.MenuList) (string, error) {
+	current, err := strconv.Atoi(item.Value)
+	if err != nil {
+		return editChoice, nil
+	}
+
+	ClickLeftButton()
+	s, err := awaitScreen(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := s.(usdxScreen.MenuEdit); !ok {
+		return "", fmt.Errorf("%s couldn't be edited", item.Name)
+	}
+	editType := editNumber // If the value can't be moved either way, it's taken to be a number.
+	for _, dir := range []int{1, -1} {
+		RotateEncoder(dir)
+		s, err := awaitScreen(ctx)
+		if err != nil {
+			return "", err
+		}
+		edit, ok := s.(usdxScreen.MenuEdit)
+		if !ok {
+			return "", fmt.Errorf("edit of %s closed", item.Name)
+		}
+		if edit.Value == item.Value { // At the end of its range.
+			continue
+		}
+		if next, err := strconv.Atoi(edit.Value); err != nil || next != current+dir {
+			editType = editChoice
+		}
+		RotateEncoder(-dir)
+		if _, err := awaitScreen(ctx); err != nil {
+			return "", err
+		}
+		break
+	}
+
+	ClickLeftButton()
+	back, err := awaitMenuList(ctx)
+	if err != nil {
+		return "", err
+	}
+	if back.Number != item.Number || back.Value != item.Value {
+		return "", fmt.Errorf("%s was left as %s, not %s", item.Name, back.Value, item.Value)
+	}
+	return editType, nil
+}
+
+// indexOfMenuItem returns the index of the menu item called name, or -1 if there's none.
+// menuSettingValue returns the value of the discovered menu item called name.
+func menuSettingValue(name string) (value string, found bool) {
+	for _, item := range MenuItems() {
+		if item.Name == name {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+func indexOfMenuItem(name string) int {
+	for _, item := range MenuItems() {
+		if item.Name == name {
+			return item.Index
+		}
+	}
+	return -1
+}
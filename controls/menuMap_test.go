@@ -0,0 +1,59 @@
+package controls
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMenuCacheWriterSkipsStaleData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "menuCache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	menuMutex.Lock()
+	savedPath, savedData := menuCachePath, menuCacheData
+	menuCachePath = filepath.Join(dir, "menu.json")
+	menuCacheData = menuCache{LastFirmware: "first", Menus: map[string][]MenuItem{}}
+	first := menuCacheWriter()
+	menuCacheData.LastFirmware = "second"
+	second := menuCacheWriter()
+	menuCachePath, menuCacheData = savedPath, savedData
+	menuMutex.Unlock()
+
+	second()
+	first() // Too late: it mustn't overwrite the later data.
+	data, err := ioutil.ReadFile(filepath.Join(dir, "menu.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cache menuCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		t.Fatal(err)
+	}
+	if cache.LastFirmware != "second" {
+		t.Errorf("cache has firmware %q, want \"second\"", cache.LastFirmware)
+	}
+}
+
+func TestIndexOfMenuItem(t *testing.T) {
+	menuMutex.Lock()
+	saved := menuItems
+	menuItems = []MenuItem{{Index: 0, Name: "Volume"}, {Index: 1, Name: "Mode"}}
+	menuMutex.Unlock()
+	defer func() {
+		menuMutex.Lock()
+		menuItems = saved
+		menuMutex.Unlock()
+	}()
+
+	for name, want := range map[string]int{"Volume": 0, "Mode": 1, "Nothing": -1} {
+		if got := indexOfMenuItem(name); got != want {
+			t.Errorf("indexOfMenuItem(%q) is %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A profile is a snapshot of every setting on the uSDX's menu, kept in a file, e.g. one each for
+// SOTA, home and digital modes. Restoring a profile only edits the settings that differ from it.
+
+// A Profile is the settings on the menu, in menu order, when it was taken.
+type Profile struct {
+	Name     string           `json:"name"`
+	Firmware string           `json:"firmware"`
+	Taken    time.Time        `json:"taken"`
+	Settings []ProfileSetting `json:"settings"`
+}
+
+type ProfileSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// A SettingDiff is a setting whose value on the radio differs from a profile's. Current is empty
+// if the setting isn't on the menu.
+type SettingDiff struct {
+	Name             string
+	Current, Profile string
+}
+
+func (d SettingDiff) String() string {
+	if d.Current == "" {
+		return fmt.Sprintf("%s: not on the menu, profile has %s", d.Name, d.Profile)
+	}
+	return fmt.Sprintf("%s: %s -> %s", d.Name, d.Current, d.Profile)
+}
+
+// TakeProfile walks the menu, and returns its settings as a profile called name.
+func TakeProfile(ctx context.Context, name string) (*Profile, error) {
+	items, err := DiscoverMenu(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{Name: name, Firmware: MenuFirmware(), Taken: time.Now()}
+	for _, item := range items {
+		p.Settings = append(p.Settings, ProfileSetting{item.Name, item.Value})
+	}
+	return p, nil
+}
+
+// SaveProfile writes p to path.
+func SaveProfile(p *Profile, path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadProfile reads a profile from path.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// DiffProfile walks the menu, and returns the settings that differ from p, in p's order.
+func DiffProfile(ctx context.Context, p *Profile) ([]SettingDiff, error) {
+	items, err := DiscoverMenu(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]string{}
+	for _, item := range items {
+		current[item.Name] = item.Value
+	}
+	var diffs []SettingDiff
+	for _, s := range p.Settings {
+		if current[s.Name] != s.Value {
+			diffs = append(diffs, SettingDiff{s.Name, current[s.Name], s.Value})
+		}
+	}
+	return diffs, nil
+}
+
+// DiffReport describes the differences between the radio and p, one setting per line.
+func DiffReport(p *Profile, diffs []SettingDiff) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "Profile %s, taken %s", p.Name, p.Taken.Format("2006-01-02 15:04"))
+	if firmware := MenuFirmware(); p.Firmware != firmware {
+		fmt.Fprintf(&report, " from %s firmware, but the radio has %s", p.Firmware, firmware)
+	}
+	if len(diffs) == 0 {
+		report.WriteString(": no differences\n")
+		return report.String()
+	}
+	fmt.Fprintf(&report, ": %d differences\n", len(diffs))
+	for _, d := range diffs {
+		fmt.Fprintf(&report, "  %v\n", d)
+	}
+	return report.String()
+}
+
+// RestoreProfile sets each setting that differs from p. It refuses a profile taken from other
+// firmware, whose settings may mean something else, unless otherFirmware is true. Before anything
+// is set, review is given the DiffReport, and the restore goes ahead only if it returns true. It
+// carries on past a setting that can't be set, and returns an error listing any that weren't,
+// including any that aren't on the menu.
+func RestoreProfile(ctx context.Context, p *Profile, otherFirmware bool, review func(report string) bool) error {
+	if firmware := MenuFirmware(); p.Firmware != firmware && !otherFirmware {
+		return fmt.Errorf("profile %s was taken from %s firmware, but the radio has %s", p.Name, p.Firmware, firmware)
+	}
+	diffs, err := DiffProfile(ctx, p)
+	if err != nil {
+		return err
+	}
+	report := DiffReport(p, diffs)
+	log.Print(report)
+	if !review(report) {
+		return fmt.Errorf("restore of profile %s was declined", p.Name)
+	}
+
+	var failures []string
+	for _, d := range diffs {
+		if d.Current == "" {
+			failures = append(failures, d.String())
+			continue
+		}
+		if err := SetSetting(ctx, d.Name, d.Profile); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("profile %s wasn't fully restored:\n%s", p.Name, strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// ProfileName is the name of a profile kept at path, e.g. "sota" for "profiles/sota.json".
+func ProfileName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
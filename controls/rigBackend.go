@@ -0,0 +1,88 @@
+package controls
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// A rigBackend carries out the actions that the CAT servers are asked for. It's the uSDX itself,
+// except when CAT sessions are replayed, when a mock that simply updates rig stands in for it.
+type rigBackend interface {
+	setFrequency(hz int64)
+	setMode(mode int)
+	setPushToTalk(on bool)
+	rotateEncoder(dir int)
+	setSetting(name, value string)
+}
+
+var backend rigBackend = usdxBackend{}
+
+// usdxBackend operates the uSDX's controls through the controller board.
+type usdxBackend struct{}
+
+func (usdxBackend) setFrequency(hz int64) {
+	SetFrequency(fmt.Sprintf("%011d", hz))
+}
+
+func (usdxBackend) setMode(mode int) {
+	SetMode(mode)
+}
+
+// The CAT servers hold rigMutex, so this can't use StartPushToTalk and EndPushToTalk.
+func (usdxBackend) setPushToTalk(on bool) {
+	if on {
+		hardwareAction(startPushToTalk)
+	} else {
+		hardwareAction(endPushToTalk)
+	}
+	rig.transmitting = on
+}
+
+func (usdxBackend) rotateEncoder(dir int) {
+	RotateEncoder(dir)
+}
+
+// setSetting edits the menu in the background, as it can take a while.
+func (usdxBackend) setSetting(name, value string) {
+	ctx, cancel := context.WithTimeout(context.Background(), settingTimeout)
+	result := queueSetSetting(ctx, name, value)
+	go func() {
+		defer cancel()
+		if err := <-result; err != nil {
+			log.Printf("Set %s to %s: %v", name, value, err)
+		}
+	}()
+}
+
+// mockBackend applies actions directly to rig, as if the uSDX had carried them out instantly.
+type mockBackend struct{}
+
+func (mockBackend) setFrequency(hz int64) {
+	if rig.vfo == vfoB {
+		rig.frequencyB = hz
+	} else {
+		rig.frequencyA = hz
+	}
+}
+
+func (mockBackend) setMode(mode int) {
+	rig.mode = mode
+}
+
+func (mockBackend) setPushToTalk(on bool) {
+	rig.transmitting = on
+}
+
+func (mockBackend) rotateEncoder(dir int) {
+}
+
+func (mockBackend) setSetting(name, value string) {
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	for i := range menuItems {
+		if menuItems[i].Name == name {
+			menuItems[i].Value = value
+		}
+	}
+}
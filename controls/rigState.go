@@ -0,0 +1,91 @@
+package controls
+
+import (
+	"strings"
+	"sync"
+	"uSDX/usdxScreen"
+)
+
+// rigState models the state of the uSDX, as far as it can be known from its display and from
+// the controls that the app has operated.
+type rigState struct {
+	frequencyA   int64 // In Hz
+	frequencyB   int64 // In Hz
+	vfo          int   // The VFO shown on the main screen, vfoA or vfoB.
+	mode         int   // One of the Kenwood mode numbers, e.g. modeUsb.
+	transmitting bool  // Push to talk is on.
+	sMeter       int   // S units, as shown by the s-meter bars.
+	poweredOn    bool  // As last reported by the controller board.
+}
+
+// The radio is usually on already when the app starts, and the controller board only reports
+// it being switched on or off.
+var rig = rigState{vfo: vfoA, mode: modeUsb, poweredOn: true}
+
+// rigMutex serializes the CAT servers' commands, and their access to rig.
+var rigMutex sync.Mutex
+
+const (
+	vfoA = 0
+	vfoB = 1
+)
+
+// The uSDX's modes, numbered as in the Kenwood MD command.
+const (
+	modeLsb = 1
+	modeUsb = 2
+	modeCw  = 3
+	modeFm  = 4
+	modeAm  = 5
+)
+
+// The labels that the uSDX shows for the modes.
+var modeLabels = map[int]string{
+	modeLsb: "LSB",
+	modeUsb: "USB",
+	modeCw:  "CW ",
+	modeFm:  "FM ",
+	modeAm:  "AM ",
+}
+
+// modeName is the mode's label without padding, as used by Hamlib and flrig.
+func modeName(mode int) string {
+	return strings.TrimSpace(modeLabels[mode])
+}
+
+func modeNamed(name string) (mode int, found bool) {
+	for m := range modeLabels {
+		if modeName(m) == name {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// updateFromMainScreen reads the rig state from the main screen.
+func (r *rigState) updateFromMainScreen(m usdxScreen.Main) {
+	if m.Vfo == "B" {
+		r.vfo = vfoB
+		r.frequencyB = m.FrequencyHz
+	} else {
+		r.vfo = vfoA
+		r.frequencyA = m.FrequencyHz
+	}
+	if mode, found := modeNamed(m.Mode); found {
+		r.mode = mode
+	}
+	r.sMeter = m.SMeter
+}
+
+func setTransmitting(on bool) {
+	rigMutex.Lock()
+	defer rigMutex.Unlock()
+	rig.transmitting = on
+}
+
+func (r *rigState) displayedVfoFrequency() int64 {
+	if r.vfo == vfoB {
+		return r.frequencyB
+	}
+	return r.frequencyA
+}
@@ -0,0 +1,276 @@
+package controls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// A subset of the Hamlib NET rigctl protocol, as spoken by rigctld. Documented at:
+//    https://github.com/Hamlib/Hamlib/blob/master/doc/man1/rigctld.1
+
+// RigctldAddr is where rigctld listens by default, and where Hamlib's NET rigctl model looks.
+const RigctldAddr = "localhost:4532"
+
+// Hamlib error codes
+const (
+	rigOk      = 0
+	rigEInval  = -1  // Invalid parameter
+	rigENAvail = -11 // Function not available
+)
+
+const rigProtocol = 0 // dump_state protocol version
+
+type rigctlCommand struct {
+	long    string // Name of the command when prefixed with a backslash, e.g. "get_freq".
+	short   byte   // Single character name, or 0 if there is none.
+	numArgs int
+	execute func(args []string) (answer string, rprt int) // An answer is only sent when rprt is rigOk.
+	isSet   bool                                          // Set commands answer with an RPRT line.
+}
+
+var rigctlCommands = []*rigctlCommand{
+	{long: "get_freq", short: 'f', execute: rigctlGetFreq},
+	{long: "set_freq", short: 'F', numArgs: 1, execute: rigctlSetFreq, isSet: true},
+	{long: "get_mode", short: 'm', execute: rigctlGetMode},
+	{long: "set_mode", short: 'M', numArgs: 2, execute: rigctlSetMode, isSet: true},
+	{long: "get_ptt", short: 't', execute: rigctlGetPtt},
+	{long: "set_ptt", short: 'T', numArgs: 1, execute: rigctlSetPtt, isSet: true},
+	{long: "get_vfo", short: 'v', execute: rigctlGetVfo},
+	{long: "chk_vfo", execute: rigctlChkVfo},
+	{long: "dump_state", execute: rigctlDumpState},
+}
+
+// ProcessRigctld accepts rigctl clients on addr, e.g. RigctldAddr, serving each one in its own
+// goroutine. If addr can't be listened on, e.g. because a real rigctld has it, the error is
+// logged and the app carries on without it.
+func ProcessRigctld(addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("rigctld server: %v", err)
+		return
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("rigctld server: %v", err)
+			return
+		}
+		go serveRigctlClient(conn)
+	}
+}
+
+func serveRigctlClient(conn net.Conn) {
+	defer conn.Close()
+	log.Printf("rigctl client connected from %s", conn.RemoteAddr())
+	client := &catClient{name: conn.RemoteAddr().String(), dialect: rigctlDialect{}}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			log.Printf("rigctl client %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "q" || line == "Q" || line == `\quit` {
+			return
+		}
+		log.Printf("RIGCTL CMD %s", line)
+		rigMutex.Lock()
+		before := transcriptRigIfRecording()
+		response := executeRigctlLine(line)
+		rigMutex.Unlock()
+		recordCatExchange(client, []byte(line), []byte(response), before)
+		log.Printf("RIGCTL RSP %q", response)
+		if _, err := conn.Write([]byte(response)); err != nil {
+			log.Printf("rigctl client %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// rigctlDialect lets rigctl sessions be recorded in CAT transcripts, and replayed. Each command is
+// one line of rigctl input, without its newline.
+type rigctlDialect struct{}
+
+func (rigctlDialect) spec() string {
+	return "rigctld"
+}
+
+func (rigctlDialect) readCommand(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	return bytes.TrimSpace(line), err
+}
+
+func (rigctlDialect) describe(cmd []byte) string {
+	return string(cmd)
+}
+
+func (rigctlDialect) undescribe(desc string) ([]byte, error) {
+	return []byte(desc), nil
+}
+
+func (rigctlDialect) execute(client *catClient, cmd []byte) []byte {
+	return []byte(executeRigctlLine(string(cmd)))
+}
+
+// executeRigctlLine runs one line of rigctl input and returns the text to send back.
+func executeRigctlLine(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return rprt(rigEInval)
+	}
+	name, args := fields[0], fields[1:]
+
+	// Short commands can be run together with their first argument, e.g. "F14074000".
+	if !strings.HasPrefix(name, `\`) && len(name) > 1 {
+		args = append([]string{name[1:]}, args...)
+		name = name[0:1]
+	}
+
+	cmd := findRigctlCommand(name)
+	if cmd == nil {
+		return rprt(rigENAvail)
+	}
+	if len(args) < cmd.numArgs {
+		return rprt(rigEInval)
+	}
+	answer, code := cmd.execute(args)
+	if code != rigOk || cmd.isSet {
+		return rprt(code)
+	}
+	return answer
+}
+
+func findRigctlCommand(name string) *rigctlCommand {
+	for _, cmd := range rigctlCommands {
+		if name == `\`+cmd.long || (cmd.short != 0 && name == string(cmd.short)) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func rprt(code int) string {
+	return fmt.Sprintf("RPRT %d\n", code)
+}
+
+func rigctlGetFreq(args []string) (string, int) {
+	return fmt.Sprintf("%d\n", rig.displayedVfoFrequency()), rigOk
+}
+
+func rigctlSetFreq(args []string) (string, int) {
+	hz, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || !frequencyInRange(int64(hz)) {
+		return "", rigEInval
+	}
+	backend.setFrequency(int64(hz))
+	return "", rigOk
+}
+
+func rigctlGetMode(args []string) (string, int) {
+	return fmt.Sprintf("%s\n%d\n", modeName(rig.mode), rigctlPassband()), rigOk
+}
+
+// rigctlPassband is the width of the uSDX's filter, in Hz, as its Filter BW setting shows it. It's
+// 0, Hamlib's "normal" passband, when the filter is Full or the menu hasn't been discovered.
+func rigctlPassband() int {
+	value, _ := menuSettingValue("Filter BW")
+	hz, _ := strconv.Atoi(value)
+	return hz
+}
+
+// Digital mode programs, e.g. fldigi and WSJT-X, ask for the packet modes, which are plain SSB
+// on the uSDX.
+var rigctlPacketModes = map[string]int{
+	"PKTLSB": modeLsb,
+	"PKTUSB": modeUsb,
+}
+
+// The passband argument is ignored, since the uSDX's filter is set from its menu.
+func rigctlSetMode(args []string) (string, int) {
+	mode, found := modeNamed(args[0])
+	if !found {
+		mode, found = rigctlPacketModes[args[0]]
+	}
+	if !found {
+		return "", rigEInval
+	}
+	backend.setMode(mode)
+	return "", rigOk
+}
+
+func rigctlGetPtt(args []string) (string, int) {
+	if rig.transmitting {
+		return "1\n", rigOk
+	}
+	return "0\n", rigOk
+}
+
+func rigctlSetPtt(args []string) (string, int) {
+	switch args[0] {
+	case "0":
+		backend.setPushToTalk(false)
+	case "1", "2", "3": // On, on with mic, on with data
+		backend.setPushToTalk(true)
+	default:
+		return "", rigEInval
+	}
+	return "", rigOk
+}
+
+func rigctlGetVfo(args []string) (string, int) {
+	if rig.vfo == vfoB {
+		return "VFOB\n", rigOk
+	}
+	return "VFOA\n", rigOk
+}
+
+// Answering 0 tells the client not to prefix commands with a VFO argument.
+func rigctlChkVfo(args []string) (string, int) {
+	return "CHKVFO 0\n", rigOk
+}
+
+// Hamlib mode bits for the modes the uSDX supports.
+const rigctlModes = 0x1 | 0x2 | 0x4 | 0x8 | 0x20 | 0x400 | 0x800 // AM, CW, USB, LSB, FM, PKTLSB, PKTUSB
+
+func rigctlDumpState(args []string) (string, int) {
+	var sb strings.Builder
+
+	add(&sb, fmt.Sprintf("%d\n", rigProtocol))
+	add(&sb, "2\n") // Rig model: NET rigctl
+	add(&sb, "2\n") // ITU region
+
+	// Frequency ranges: start, end, modes, low power, high power (mW), VFOs, antennas
+	add(&sb, fmt.Sprintf("100000.000000 30000000.000000 0x%x -1 -1 0x3 0x1\n", rigctlModes))
+	add(&sb, "0 0 0 0 0 0 0\n")
+	add(&sb, fmt.Sprintf("1800000.000000 30000000.000000 0x%x 1000 5000 0x3 0x1\n", rigctlModes))
+	add(&sb, "0 0 0 0 0 0 0\n")
+
+	add(&sb, fmt.Sprintf("0x%x 10\n", rigctlModes)) // Tuning step
+	add(&sb, "0 0\n")
+	add(&sb, fmt.Sprintf("0x%x 2400\n", rigctlModes)) // Filter width
+	add(&sb, "0 0\n")
+	add(&sb, "0\n")   // Max RIT
+	add(&sb, "0\n")   // Max XIT
+	add(&sb, "0\n")   // Max IF shift
+	add(&sb, "0\n")   // Announces
+	add(&sb, "0\n")   // Preamp list
+	add(&sb, "0\n")   // Attenuator list
+	add(&sb, "0x0\n") // has_get_func
+	add(&sb, "0x0\n") // has_set_func
+	add(&sb, "0x0\n") // has_get_level
+	add(&sb, "0x0\n") // has_set_level
+	add(&sb, "0x0\n") // has_get_parm
+	add(&sb, "0x0\n") // has_set_parm
+
+	return sb.String(), rigOk
+}
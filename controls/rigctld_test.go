@@ -0,0 +1,40 @@
+package controls
+
+import "testing"
+
+func TestRigctlModes(t *testing.T) {
+	savedRig, savedBackend := rig, backend
+	defer func() { rig, backend = savedRig, savedBackend }()
+	backend = mockBackend{}
+	rig = rigState{vfo: vfoA, mode: modeUsb, poweredOn: true}
+
+	savedItems := menuItems
+	defer func() { menuItems = savedItems }()
+
+	cases := []struct {
+		filter     string // The Filter BW setting, or "" if the menu hasn't been discovered.
+		line, want string
+	}{
+		{"", "m", "USB\n0\n"},
+		{"", "M PKTLSB 0", "RPRT 0\n"},
+		{"", "m", "LSB\n0\n"},
+		{"", `\set_mode PKTUSB 3000`, "RPRT 0\n"},
+		{"", "m", "USB\n0\n"},
+		{"", "M CW 0", "RPRT 0\n"},
+		{"", "m", "CW\n0\n"},
+		{"", "M PKTFM 0", "RPRT -1\n"},
+		{"500", "m", "CW\n500\n"},
+		{"Full", "m", "CW\n0\n"},
+		{"", "", "RPRT -1\n"},
+		{"", " \t", "RPRT -1\n"},
+	}
+	for _, c := range cases {
+		menuItems = nil
+		if c.filter != "" {
+			menuItems = []MenuItem{{Name: "Filter BW", Value: c.filter, EditType: editChoice}}
+		}
+		if got := executeRigctlLine(c.line); got != c.want {
+			t.Errorf("%q with filter %q got %q, want %q", c.line, c.filter, got, c.want)
+		}
+	}
+}
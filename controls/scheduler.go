@@ -0,0 +1,214 @@
+package controls
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// The high level operations share the radio's controls and its display, so a single scheduler
+// runs them one at a time, in the order they're asked for. An operation that's asked for while
+// one of the same kind is waiting takes its place in the queue, e.g. a burst of CAT FA commands,
+// from turning a logging program's dial, only sets the last frequency.
+
+// Kinds of operation, which replace waiting operations of the same kind.
+const (
+	kindCursor    = "cursor"
+	kindFrequency = "frequency"
+	kindMode      = "mode"
+	kindRefresh   = "refresh"
+	kindMenu      = "menu"
+	kindSetting   = "setting " // Followed by the setting's name and value.
+)
+
+// An operation is a sequence that's waiting to be run, or running.
+type operation struct {
+	desc    string // E.g. "Set frequency"
+	kind    string // Empty if it's never replaced.
+	seq     func(ctx context.Context) error
+	callers []caller // Including those of the operations it replaced.
+}
+
+type caller struct {
+	ctx    context.Context
+	result chan error
+}
+
+// OperationStatus is what the scheduler is doing.
+type OperationStatus struct {
+	Running string   // The running operation, or empty if there's none.
+	Queued  []string // The waiting operations, in the order they'll run.
+}
+
+// Depth is the number of operations that have yet to finish.
+func (s OperationStatus) Depth() int {
+	if s.Running == "" {
+		return len(s.Queued)
+	}
+	return len(s.Queued) + 1
+}
+
+func (s OperationStatus) String() string {
+	switch {
+	case s.Depth() == 0:
+		return "idle"
+	case s.Running == "":
+		return fmt.Sprintf("%d queued", len(s.Queued))
+	case len(s.Queued) == 0:
+		return s.Running
+	default:
+		return fmt.Sprintf("%s, %d queued", s.Running, len(s.Queued))
+	}
+}
+
+var (
+	schedulerMutex sync.Mutex // Guards the variables below.
+	operations     []*operation
+	running        *operation
+)
+
+var operationQueued = make(chan bool, 1)
+var operationsChanged = make(chan bool, 1)
+
+func init() {
+	go runOperations()
+}
+
+// Operations returns what the scheduler is doing.
+func Operations() OperationStatus {
+	schedulerMutex.Lock()
+	defer schedulerMutex.Unlock()
+	var status OperationStatus
+	if running != nil {
+		status.Running = running.desc
+	}
+	for _, op := range operations {
+		status.Queued = append(status.Queued, op.desc)
+	}
+	return status
+}
+
+// OperationsChanged receives a value after an operation is queued, starts, or finishes, e.g. so
+// that the GUI can show the scheduler's status.
+func OperationsChanged() <-chan bool {
+	return operationsChanged
+}
+
+// enqueue queues seq to be run for ctx, and returns the channel that its result will be sent
+// on. It replaces any waiting operation of the same kind, in its place in the queue, and the
+// callers of the replaced operation get the result of seq. seq runs until all of its callers'
+// contexts are done.
+func enqueue(ctx context.Context, desc, kind string, seq func(ctx context.Context) error) <-chan error {
+	result := make(chan error, 1)
+	op := &operation{desc: desc, kind: kind, seq: seq, callers: []caller{{ctx, result}}}
+
+	schedulerMutex.Lock()
+	replaced := false
+	if kind != "" {
+		for i, waiting := range operations {
+			if waiting.kind == kind {
+				op.callers = append(waiting.callers, op.callers...)
+				operations[i] = op
+				replaced = true
+				break
+			}
+		}
+	}
+	if !replaced {
+		operations = append(operations, op)
+	}
+	schedulerMutex.Unlock()
+
+	notify(operationQueued)
+	notify(operationsChanged)
+	return result
+}
+
+// failed returns a result channel for an operation that couldn't be queued.
+func failed(err error) <-chan error {
+	result := make(chan error, 1)
+	result <- err
+	return result
+}
+
+// schedule queues seq, and waits for its result, or for ctx to be done.
+func schedule(ctx context.Context, desc, kind string, seq func(ctx context.Context) error) error {
+	return await(ctx, enqueue(ctx, desc, kind, seq))
+}
+
+func await(ctx context.Context, result <-chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runOperations() {
+	for range operationQueued {
+		for {
+			schedulerMutex.Lock()
+			if len(operations) == 0 {
+				running = nil
+				schedulerMutex.Unlock()
+				notify(operationsChanged)
+				break
+			}
+			op := operations[0]
+			operations = operations[1:]
+			running = op
+			schedulerMutex.Unlock()
+			notify(operationsChanged)
+
+			ctx, cancel := op.callersContext()
+			err := ctx.Err() // Its callers may have given up while it was waiting.
+			if err == nil {
+				err = runSequence(ctx, op.seq)
+			}
+			cancel()
+			for _, c := range op.callers {
+				if err != nil && c.ctx.Err() != nil {
+					c.result <- c.ctx.Err() // The caller's own reason for giving up.
+				} else {
+					c.result <- err
+				}
+			}
+		}
+	}
+}
+
+// callersContext returns a context that's done once all of the operation's callers' contexts
+// are done, and a func to release it.
+func (op *operation) callersContext() (context.Context, context.CancelFunc) {
+	if len(op.callers) == 1 {
+		return context.WithCancel(op.callers[0].ctx)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	givenUp := true
+	for _, c := range op.callers {
+		givenUp = givenUp && c.ctx.Err() != nil
+	}
+	if givenUp {
+		cancel()
+	}
+	go func() {
+		for _, c := range op.callers {
+			select {
+			case <-c.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// notify sends on c, unless a value is already waiting to be received.
+func notify(c chan bool) {
+	select {
+	case c <- true:
+	default:
+	}
+}
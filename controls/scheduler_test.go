@@ -0,0 +1,61 @@
+package controls
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnqueueMergesInPlace(t *testing.T) {
+	blocker, cancelBlocker := context.WithCancel(context.Background())
+	first, cancelFirst := context.WithCancel(context.Background())
+	other, cancelOther := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelBlocker()
+	defer cancelFirst()
+	defer cancelOther()
+	defer cancelSecond()
+	seq := func(ctx context.Context) error { // Each operation runs until its context is done.
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	enqueue(blocker, "Blocker", "", seq)
+	waitForOperations(t, func(s OperationStatus) bool { return s.Running == "Blocker" })
+	firstResult := enqueue(first, "Set frequency 1", kindFrequency, seq)
+	enqueue(other, "Set mode", kindMode, seq)
+	secondResult := enqueue(second, "Set frequency 2", kindFrequency, seq)
+	want := []string{"Set frequency 2", "Set mode"}
+	if queued := Operations().Queued; !reflect.DeepEqual(queued, want) {
+		t.Errorf("queued %q, want %q", queued, want)
+	}
+
+	// The merged operation keeps running while either of its callers is waiting.
+	cancelSecond()
+	cancelBlocker()
+	waitForOperations(t, func(s OperationStatus) bool { return s.Running == "Set frequency 2" })
+	time.Sleep(100 * time.Millisecond)
+	if running := Operations().Running; running != "Set frequency 2" {
+		t.Errorf("running %q after one caller gave up, want \"Set frequency 2\"", running)
+	}
+	cancelFirst()
+	for _, result := range []<-chan error{firstResult, secondResult} {
+		if err := <-result; err != context.Canceled {
+			t.Errorf("result %v, want %v", err, context.Canceled)
+		}
+	}
+	cancelOther()
+	waitForOperations(t, func(s OperationStatus) bool { return s.Depth() == 0 })
+}
+
+func waitForOperations(t *testing.T, ok func(s OperationStatus) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !ok(Operations()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduler is %v", Operations())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,175 @@
+package controls
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"uSDX/usdxScreen"
+)
+
+// settingTimeout is the longest that SetSetting takes, e.g. to step Noise Gate from 0 to 255.
+const settingTimeout = 2 * time.Minute
+
+// SetSetting sets the menu setting called name to value, as the menu shows it, e.g. "USB" or
+// "20". It goes into the menu, edits the setting, checks the value that's shown once the edit
+// is confirmed, and goes back to the main screen. It returns an error if the setting isn't on
+// the menu, the value can't be reached, the radio stops responding, or ctx is done. The
+// settingTimeout includes any wait for other operations to finish.
+func SetSetting(ctx context.Context, name, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, settingTimeout)
+	defer cancel()
+	if err := await(ctx, queueSetSetting(ctx, name, value)); err != nil {
+		return fmt.Errorf("setting %s to %s: %v", name, value, err)
+	}
+	return nil
+}
+
+// queueSetSetting queues the edit, and records the value once the menu has confirmed it. Only
+// sets of the same value are merged, so that each caller's result is for its own value.
+func queueSetSetting(ctx context.Context, name, value string) <-chan error {
+	return enqueue(ctx, "Set "+name, kindSetting+name+" "+value, func(ctx context.Context) error {
+		if err := waitForMainScreen(ctx); err != nil {
+			return err
+		}
+		if err := editSetting(ctx, name, value); err != nil {
+			return err
+		}
+		recordSettingValue(name, value)
+		return nil
+	})
+}
+
+func editSetting(ctx context.Context, name, value string) error {
+	targetIndex := indexOfMenuItem(name)
+	if targetIndex < 0 {
+		if MenuItems() == nil {
+			return fmt.Errorf("menu hasn't been discovered")
+		}
+		return fmt.Errorf("no such setting")
+	}
+	editType := MenuItems()[targetIndex].EditType
+
+	ClickLeftButton()
+	item, err := awaitMenuList(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Move to the setting.
+	for item.Name != name {
+		index := indexOfMenuItem(item.Name)
+		if index < 0 {
+			return fmt.Errorf("menu shows %s, which wasn't discovered", item.Name)
+		}
+		if index < targetIndex {
+			RotateEncoderClockwise()
+		} else {
+			RotateEncoderCounterclockwise()
+		}
+		next, err := awaitMenuList(ctx)
+		if err != nil {
+			return err
+		}
+		if next.Number == item.Number {
+			return fmt.Errorf("stuck on %s", item.Name)
+		}
+		item = next
+	}
+	if item.Value == value {
+		return nil
+	}
+
+	// Edit its value.
+	ClickLeftButton()
+	s, err := awaitScreen(ctx)
+	if err != nil {
+		return err
+	}
+	edit, ok := s.(usdxScreen.MenuEdit)
+	if !ok {
+		return fmt.Errorf("%s couldn't be edited", name)
+	}
+	if reached, err := rotateToValue(ctx, edit, editType, value); err != nil {
+		// Put it back as it was, even if ctx is done, so that the edit can be confirmed or
+		// abandoned without changing the setting.
+		putBackCtx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+		defer cancel()
+		if s, _ := awaitScreen(putBackCtx); s != nil { // The last step may not have been seen.
+			if last, ok := s.(usdxScreen.MenuEdit); ok {
+				reached = last
+			}
+		}
+		if _, putBackErr := rotateToValue(putBackCtx, reached, editType, edit.Value); putBackErr != nil {
+			return fmt.Errorf("%v, and putting it back to %s failed: %v", err, edit.Value, putBackErr)
+		}
+		return err
+	}
+
+	// Confirm it, and check what's shown.
+	ClickLeftButton()
+	item, err = awaitMenuList(ctx)
+	if err != nil {
+		return err
+	}
+	if item.Name != name || item.Value != value {
+		return fmt.Errorf("menu shows %s %s after the edit", item.Name, item.Value)
+	}
+	return nil
+}
+
+// rotateToValue turns the encoder until the setting being edited shows value. A number, by
+// editType, is turned towards value. A choice is turned clockwise until it stops changing, then
+// counterclockwise, so that it finds value whichever side of the current choice it's on.
+// It returns the edit as it was left.
+func rotateToValue(ctx context.Context, edit usdxScreen.MenuEdit, editType, value string) (reached usdxScreen.MenuEdit, err error) {
+	dirs := []int{1, -1}
+	if editType == editNumber {
+		current, currentErr := strconv.Atoi(edit.Value)
+		target, targetErr := strconv.Atoi(value)
+		if currentErr != nil || targetErr != nil {
+			return edit, fmt.Errorf("%q isn't a number", value)
+		}
+		if target < current {
+			dirs = []int{-1}
+		} else {
+			dirs = []int{1}
+		}
+	}
+
+	for _, dir := range dirs {
+		seen := map[string]bool{edit.Value: true}
+		for edit.Value != value {
+			RotateEncoder(dir)
+			s, err := awaitScreen(ctx)
+			if err != nil {
+				return edit, err
+			}
+			next, ok := s.(usdxScreen.MenuEdit)
+			if !ok {
+				return edit, fmt.Errorf("edit closed")
+			}
+			if seen[next.Value] { // At the end of the range, or back round to the start.
+				break
+			}
+			seen[next.Value] = true
+			edit = next
+		}
+		if edit.Value == value {
+			return edit, nil
+		}
+	}
+	return edit, fmt.Errorf("value not found")
+}
+
+// recordSettingValue updates the value of a discovered menu item, and the cache.
+func recordSettingValue(name, value string) {
+	menuMutex.Lock()
+	defer menuMutex.Unlock()
+	for i := range menuItems {
+		if menuItems[i].Name == name {
+			menuItems[i].Value = value
+			saveMenuCache()
+		}
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"image/color"
 	"log"
 	"os"
-	"uSDX/ambEmuLcd"
 	"uSDX/controls"
 )
 
@@ -28,7 +27,7 @@ type (
 
 var (
 	theme            = material.NewTheme(gofont.Collection())
-	xPixels, yPixels = ambEmuLcd.NumPixels()
+	xPixels, yPixels = usdxLcd.NumPixels()
 	rendPixSize      = float32(4) // This is the RENDERED displaySize of an LCD pixel
 	wPixels          = float32(xPixels) * rendPixSize
 	hPixels          = float32(yPixels) * rendPixSize
@@ -48,7 +47,7 @@ func gui(loop func(*app.Window, chan interface{}) error, lcdEvents chan interfac
 
 	w := app.NewWindow(
 		app.Title("uSDX Controller"),
-		app.Size(Px(float32(displaySize.X)), Px(150)),
+		app.Size(Px(float32(displaySize.X)), Px(170)),
 	)
 
 	if err := loop(w, lcdEvents); err != nil {
@@ -103,7 +102,7 @@ func handleWindowEvent(iEvt event.Event) (stop bool, err error) {
 		err = evt.Err
 
 	case system.FrameEvent:
-		ambEmuLcd.UpdatePixels() // This updates the pixel state in the LCD emulator.
+		usdxLcd.UpdatePixels() // This updates the pixel state in the LCD emulator.
 		gtx := layout.NewContext(&ops, evt)
 		flex := layout.Flex{
 			Axis:    layout.Vertical,
@@ -111,6 +110,7 @@ func handleWindowEvent(iEvt event.Event) (stop bool, err error) {
 		}
 		flex.Layout(gtx,
 			layout.Rigid(func(gtx C) D { return layoutLcdDisplay(gtx) }),
+			layout.Rigid(func(gtx C) D { return layoutOperationStatus(gtx) }),
 			layout.Flexed(0.5, func(gtx C) D { return layoutButtons(gtx) }),
 		)
 		evt.Frame(gtx.Ops)
@@ -147,6 +147,15 @@ func handlePointerEvent(evt pointer.Event) {
 	}
 }
 
+// layoutOperationStatus shows what the high level controls are doing, e.g. setting the frequency
+// for a CAT client, and how many operations are waiting.
+func layoutOperationStatus(gtx C) D {
+	inset := layout.Inset{Left: Px(10), Top: Px(4)}
+	return inset.Layout(gtx, func(gtx C) D {
+		return material.Caption(theme, controls.Operations().String()).Layout(gtx)
+	})
+}
+
 func layoutButtons(gtx C) D {
 	inset := layout.UniformInset(Px(10))
 	return inset.Layout(gtx, func(gtx C) D {
@@ -178,12 +187,22 @@ func layoutLcdDisplay(gtx C) D {
 	paint.ColorOp{Color: color.RGBA{R: 0x1f, G: 0x1f, B: 0xff, A: 0xFF}}.Add(gtx.Ops)
 	paint.PaintOp{Rect: f32.Rect(0, 0, wPixels+2*dm, hPixels+2*dm)}.Add(gtx.Ops)
 
+	if !controls.RadioPoweredOn() {
+		gtx.Constraints = layout.Exact(displaySize)
+		layout.Center.Layout(gtx, func(gtx C) D {
+			label := material.Body1(theme, "radio off")
+			label.Color = color.RGBA{R: 0xf0, G: 0xf0, B: 0xff, A: 0xFF}
+			return label.Layout(gtx)
+		})
+		return D{Size: displaySize}
+	}
+
 	op.Offset(f32.Pt(dm, dm)).Add(gtx.Ops)
 
 	for iX := 0; iX < xPixels; iX++ {
 		for iY := 0; iY < yPixels; iY++ {
 			var r, g, b uint8
-			switch ambEmuLcd.PixelState(iX, iY) {
+			switch usdxLcd.PixelState(iX, iY) {
 			case -1:
 				continue
 			case 0:
@@ -0,0 +1,207 @@
+//go:build cgo
+// +build cgo
+
+package hd44780_test
+
+// The conformance test checks the pure Go hd44780 against the C vrEmuLcd it was ported from, by
+// running the same scripts of commands and data through both and comparing their pixels, DDRAM,
+// cursor address and reads after every step. It needs cgo, for vrEmuLcd.
+
+import (
+	"fmt"
+	"testing"
+	"uSDX/hd44780"
+	"uSDX/hd44780/vrEmuLcd"
+)
+
+// A step is a command (RS low) or data byte (RS high) written to the LCD, or a read.
+type step struct {
+	rs   byte
+	b    byte
+	read bool
+}
+
+func cmd(bs ...byte) []step {
+	var steps []step
+	for _, b := range bs {
+		steps = append(steps, step{rs: 0, b: b})
+	}
+	return steps
+}
+
+func data(s string) []step {
+	var steps []step
+	for i := 0; i < len(s); i++ {
+		steps = append(steps, step{rs: 1, b: s[i]})
+	}
+	return steps
+}
+
+func reads(n int) []step {
+	var steps []step
+	for i := 0; i < n; i++ {
+		steps = append(steps, step{rs: 1, read: true})
+	}
+	return steps
+}
+
+func allChars(from, to int) string {
+	var bs []byte
+	for c := from; c <= to; c++ {
+		bs = append(bs, byte(c))
+	}
+	return string(bs)
+}
+
+func script(parts ...[]step) []step {
+	var steps []step
+	for _, p := range parts {
+		steps = append(steps, p...)
+	}
+	return steps
+}
+
+// The glyph rows of a CGRAM character, 5 bits each.
+var arrowGlyph = string([]byte{0x04, 0x0e, 0x15, 0x04, 0x04, 0x04, 0x04, 0x00})
+
+type conformanceCase struct {
+	name       string
+	cols, rows int
+	rom        hd44780.Rom
+	steps      []step
+}
+
+var cases = []conformanceCase{
+	{"hello", 16, 2, hd44780.RomA02, script(
+		data("Hello"), cmd(0xC0), data("world!"))},
+	{"rom a00", 16, 2, hd44780.RomA00, script(
+		data(allChars(0x20, 0x2F)), cmd(0xC0), data(allChars(0xA0, 0xAF)))},
+	{"rom a02 all", 16, 2, hd44780.RomA02, script(
+		data(allChars(0x10, 0xFF)))},
+	{"rom a00 all", 20, 4, hd44780.RomA00, script(
+		data(allChars(0x10, 0xFF)))},
+	{"cgram", 16, 2, hd44780.RomA02, script(
+		cmd(0x40), data(arrowGlyph), cmd(0x48), data(arrowGlyph[1:]),
+		cmd(0x80), data(allChars(0, 15)), cmd(0xC0), data(allChars(0, 15)))},
+	{"cgram read back", 16, 2, hd44780.RomA02, script(
+		cmd(0x50), data(arrowGlyph), cmd(0x50), reads(10), cmd(0x80), data("ab"), cmd(0x80), reads(3))},
+	{"cgram decrement wrap", 16, 2, hd44780.RomA02, script(
+		cmd(0x04, 0x41), data(arrowGlyph), cmd(0x06, 0x80), data(allChars(0, 15)))},
+	{"cursor", 16, 2, hd44780.RomA02, script(
+		cmd(0x0E), data("abc"), cmd(0x10, 0x10), cmd(0x14), cmd(0xC5))},
+	{"cursor off", 16, 2, hd44780.RomA02, script(
+		cmd(0x0E), data("abc"), cmd(0x0C))},
+	{"display off", 16, 2, hd44780.RomA02, script(
+		data("abc"), cmd(0x08), data("def"), cmd(0x0C))},
+	{"display shift", 16, 2, hd44780.RomA02, script(
+		data("0123456789ABCDEFGHIJ"), cmd(0x18, 0x18, 0x18), cmd(0x1C), cmd(0x02), data("x"))},
+	{"entry shift", 16, 2, hd44780.RomA02, script(
+		cmd(0x07), data("shifting along"), cmd(0x05), data("back"))},
+	{"decrement", 16, 2, hd44780.RomA02, script(
+		cmd(0x04, 0x8F), data("backwards"), cmd(0x80), data("wrap"))},
+	{"wrap", 8, 1, hd44780.RomA02, script(
+		cmd(0xFE), data("around the end of DDRAM"))},
+	{"clear and home", 16, 2, hd44780.RomA02, script(
+		data("gone"), cmd(0x01), data("x"), cmd(0x02), data("y"))},
+	{"four rows", 20, 4, hd44780.RomA02, script(
+		data("line 1 of four rows, then line 3"), cmd(0xC0), data("line 2 of four rows, then line 4 "), cmd(0x0E))},
+	{"usdx startup", 16, 2, hd44780.RomA02, script(
+		cmd(0x33, 0x32, 0x28, 0x0C, 0x06, 0x01),
+		cmd(0x48), data(arrowGlyph), cmd(0x80), data("\x01uSDX\x01"),
+		cmd(0xC0), data("\x06 7,074,00 USB"), cmd(0x0D, 0xC8))},
+}
+
+func TestConformance(t *testing.T) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := check(c); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func check(c conformanceCase) error {
+	goLcd := hd44780.New(c.cols, c.rows, c.rom)
+	cLcd := vrEmuLcd.New(c.cols, c.rows, c.rom == hd44780.RomA00)
+	defer cLcd.Destroy()
+
+	if err := compare(goLcd, cLcd); err != nil {
+		return fmt.Errorf("new: %v", err)
+	}
+	for i, s := range c.steps {
+		switch {
+		case s.read:
+			if goData, cData := goLcd.ReadData(), cLcd.ReadData(); goData != cData {
+				return fmt.Errorf("step %d: read %02X, want %02X", i, goData, cData)
+			}
+		case s.rs == 0:
+			goLcd.SendCommand(s.b)
+			cLcd.SendCommand(s.b)
+		default:
+			goLcd.WriteData(s.b)
+			cLcd.WriteData(s.b)
+		}
+		if err := compare(goLcd, cLcd); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// compare compares the state of the two LCDs. The C cursor blinks by CPU time, so its pixels
+// must match those of either blink phase. When the display is off, vrEmuLcd fails to blank all
+// but the first pixel of each row (its loop continues past the pixel increment), so instead
+// hd44780's pixels must all be off.
+func compare(goLcd *hd44780.Lcd, cLcd *vrEmuLcd.Lcd) error {
+	if goAddr, cAddr := goLcd.ReadAddress(), cLcd.ReadAddress(); goAddr != cAddr {
+		return fmt.Errorf("address %02X, want %02X", goAddr, cAddr)
+	}
+	if goOffset, cOffset := goLcd.CursorOffset(), cLcd.CursorOffset(); goOffset != cOffset {
+		return fmt.Errorf("cursor offset %02X, want %02X", goOffset, cOffset)
+	}
+	if goRam, cRam := goLcd.DisplayRam(), cLcd.DisplayRam(); string(goRam) != string(cRam) {
+		return fmt.Errorf("DDRAM %q, want %q", goRam, cRam)
+	}
+
+	goWidth, goHeight := goLcd.NumPixels()
+	cWidth, cHeight := cLcd.NumPixels()
+	if goWidth != cWidth || goHeight != cHeight {
+		return fmt.Errorf("%dx%d pixels, want %dx%d", goWidth, goHeight, cWidth, cHeight)
+	}
+	if !goLcd.DisplayOn() {
+		goLcd.UpdatePixels()
+		return checkBlank(goLcd, goWidth, goHeight)
+	}
+	cLcd.UpdatePixels()
+	var mismatch error
+	for _, blinkShowing := range []bool{false, true} {
+		goLcd.Render(blinkShowing)
+		if mismatch = comparePixels(goLcd, cLcd, cWidth, cHeight); mismatch == nil {
+			return nil
+		}
+	}
+	return mismatch
+}
+
+func comparePixels(goLcd *hd44780.Lcd, cLcd *vrEmuLcd.Lcd, width, height int) error {
+	for y := -1; y <= height; y++ {
+		for x := 0; x < width; x++ {
+			if goPixel, cPixel := goLcd.PixelState(x, y), cLcd.PixelState(x, y); goPixel != cPixel {
+				return fmt.Errorf("pixel %d,%d is %d, want %d", x, y, goPixel, cPixel)
+			}
+		}
+	}
+	return nil
+}
+
+func checkBlank(goLcd *hd44780.Lcd, width, height int) error {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if goLcd.PixelState(x, y) == 1 {
+				return fmt.Errorf("pixel %d,%d is on with the display off", x, y)
+			}
+		}
+	}
+	return nil
+}
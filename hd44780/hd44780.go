@@ -0,0 +1,412 @@
+package hd44780
+
+// A pure Go emulation of a Hitachi HD44780 character LCD controller, ported from Troy Schrapel's
+// vrEmuLcd (MIT license, https://github.com/visrealm/VrEmuLcd) including AMB's changes to it.
+// It emulates DDRAM and CGRAM, the A00 and A02 character ROMs, the cursor and its blinking,
+// display shifting and rendering to pixels. Its conformance test checks it against the original.
+
+import "time"
+
+// Instruction bits
+const (
+	CmdClear = 0b00000001
+	CmdHome  = 0b00000010
+
+	CmdEntryMode          = 0b00000100
+	CmdEntryModeIncrement = 0b00000010
+	CmdEntryModeDecrement = 0b00000000
+	CmdEntryModeShift     = 0b00000001
+
+	CmdDisplay            = 0b00001000
+	CmdDisplayOn          = 0b00000100
+	CmdDisplayCursor      = 0b00000010
+	CmdDisplayCursorBlink = 0b00000001
+
+	CmdShift        = 0b00010000
+	CmdShiftCursor  = 0b00000000
+	CmdShiftDisplay = 0b00001000
+	CmdShiftLeft    = 0b00000000
+	CmdShiftRight   = 0b00000100
+
+	CmdFunction      = 0b00100000
+	CmdFunction1Line = 0b00000000
+	CmdFunction2Line = 0b00001000
+
+	CmdSetCgramAddr = 0b01000000
+	CmdSetDdramAddr = 0b10000000
+)
+
+// A Rom is one of the character sets that HD44780s are made with.
+type Rom int
+
+const (
+	RomA00 Rom = iota // Japanese
+	RomA02            // European
+)
+
+const (
+	charWidth  = 5
+	charHeight = 8
+
+	ddramSize        = 0x80
+	dataWidth1Row    = 0x80
+	dataWidth2Rows   = 0x40
+	dataWidth4Rows   = 0x20
+	minCols, maxCols = 8, 40
+	minRows, maxRows = 1, 4
+
+	cgramChars       = 16
+	cgramSize        = cgramChars * charHeight
+	defaultCgramByte = 0xaa
+	romChars         = 256 - cgramChars
+
+	cursorMask        = CmdDisplayCursorBlink | CmdDisplayCursor
+	cursorBlinkPeriod = 350 * time.Millisecond
+)
+
+var rowOffsets = [maxRows]int{0x00, 0x40, 0x14, 0x54}
+
+// An Lcd is an HD44780 driving a display of Cols x Rows characters.
+type Lcd struct {
+	Cols, Rows int
+
+	// Clock gives the time used for blinking the cursor.
+	Clock func() time.Time
+
+	rom            Rom
+	entryModeFlags byte
+	displayFlags   byte
+	scrollOffset   int
+	dataWidth      int
+
+	ddram    [ddramSize]byte
+	ddPtr    int
+	cgram    [cgramSize]byte // Each character is stored as 5 column bytes, like the ROMs, padded to 8.
+	cgPtr    int
+	cgActive bool // Whether reads and writes go to CGRAM rather than DDRAM.
+
+	pixels                    []int8
+	pixelsWidth, pixelsHeight int
+}
+
+// New returns an Lcd of cols (8 to 40) x rows (1, 2 or 4) characters, with the display on.
+func New(cols, rows int, rom Rom) *Lcd {
+	if cols < minCols {
+		cols = minCols
+	} else if cols > maxCols {
+		cols = maxCols
+	}
+	if rows < minRows {
+		rows = minRows
+	} else if rows > maxRows {
+		rows = maxRows
+	}
+	if rows == 3 {
+		rows = 2
+	}
+
+	lcd := &Lcd{
+		Cols:           cols,
+		Rows:           rows,
+		Clock:          time.Now,
+		rom:            rom,
+		entryModeFlags: CmdEntryModeIncrement,
+		displayFlags:   CmdDisplayOn, // AMB change, was 0x00.
+		pixelsWidth:    cols*(charWidth+1) - 1,
+		pixelsHeight:   rows*(charHeight+1) - 1,
+	}
+	switch rows {
+	case 1:
+		lcd.dataWidth = dataWidth1Row
+	case 2:
+		lcd.dataWidth = dataWidth2Rows
+	case 4:
+		lcd.dataWidth = dataWidth4Rows
+	}
+	for i := range lcd.ddram {
+		lcd.ddram[i] = ' '
+	}
+	for i := range lcd.cgram {
+		lcd.cgram[i] = defaultCgramByte
+	}
+	lcd.pixels = make([]int8, lcd.pixelsWidth*lcd.pixelsHeight)
+	for i := range lcd.pixels {
+		lcd.pixels[i] = -1
+	}
+	lcd.UpdatePixels()
+	return lcd
+}
+
+// increment moves the DDRAM address on, skipping to the correct line and wrapping to the start.
+func (lcd *Lcd) increment() {
+	lcd.ddPtr++
+	if lcd.Rows > 2 { // 4 row mode's funky addressing scheme
+		if lcd.ddPtr == 0x28 {
+			lcd.ddPtr = 0x40
+		} else if lcd.ddPtr == 0x68 || lcd.ddPtr >= ddramSize {
+			lcd.ddPtr = 0
+		}
+	} else if lcd.ddPtr >= ddramSize {
+		lcd.ddPtr = 0
+	}
+}
+
+// decrement moves the DDRAM address back, skipping to the correct line and wrapping to the end.
+func (lcd *Lcd) decrement() {
+	lcd.ddPtr--
+	if lcd.Rows > 2 {
+		if lcd.ddPtr == -1 {
+			lcd.ddPtr = 0x67 // vrEmuLcd then adds 0x80, running off the end of DDRAM.
+		} else if lcd.ddPtr == 0x39 {
+			lcd.ddPtr = 0x27
+		}
+	} else if lcd.ddPtr == -1 {
+		lcd.ddPtr += ddramSize
+	}
+}
+
+// doShift moves the cursor or shifts the display, as the entry mode says.
+func (lcd *Lcd) doShift() {
+	increment := lcd.entryModeFlags&CmdEntryModeIncrement != 0
+	if lcd.cgActive {
+		if increment {
+			lcd.cgPtr = (lcd.cgPtr + 1) % cgramSize
+		} else {
+			lcd.cgPtr = (lcd.cgPtr + cgramSize - 1) % cgramSize
+		}
+		return
+	}
+
+	if lcd.entryModeFlags&CmdEntryModeShift != 0 {
+		if increment {
+			lcd.scrollOffset++
+		} else {
+			lcd.scrollOffset--
+		}
+	}
+	if increment {
+		lcd.increment()
+	} else {
+		lcd.decrement()
+	}
+}
+
+// SendCommand sends an instruction to the LCD, i.e. writes with RS low.
+func (lcd *Lcd) SendCommand(command byte) {
+	switch {
+	case command&CmdSetDdramAddr != 0:
+		lcd.ddPtr = int(command & 0x7f)
+		lcd.cgActive = false
+
+	case command&CmdSetCgramAddr != 0:
+		lcd.cgPtr = int(command & 0x3f)
+		lcd.cgActive = true
+
+	case command&CmdFunction != 0:
+		// Ignored
+
+	case command&CmdShift != 0:
+		if command&CmdShiftDisplay != 0 {
+			if command&CmdShiftRight != 0 {
+				lcd.scrollOffset--
+			} else {
+				lcd.scrollOffset++
+			}
+		} else {
+			if command&CmdShiftRight != 0 {
+				lcd.increment()
+			} else {
+				lcd.decrement()
+			}
+		}
+
+	case command&CmdDisplay != 0:
+		lcd.displayFlags = command
+
+	case command&CmdEntryMode != 0:
+		lcd.entryModeFlags = command
+
+	case command&CmdHome != 0:
+		lcd.ddPtr = 0
+		lcd.scrollOffset = 0
+
+	case command&CmdClear != 0:
+		for i := range lcd.ddram {
+			lcd.ddram[i] = ' '
+		}
+		lcd.ddPtr = 0
+		lcd.scrollOffset = 0
+	}
+}
+
+// WriteData writes data to DDRAM or CGRAM, i.e. writes with RS high.
+// In CGRAM, the low 5 bits of data are one pixel row of a character.
+func (lcd *Lcd) WriteData(data byte) {
+	if lcd.cgActive {
+		row := lcd.cgPtr % charHeight
+		start := lcd.cgPtr - row
+		for i := 0; i < charWidth; i++ {
+			if data&((1<<(charWidth-1))>>i) != 0 {
+				lcd.cgram[start+i] |= 0x80 >> row
+			} else {
+				lcd.cgram[start+i] &^= 0x80 >> row
+			}
+		}
+	} else {
+		lcd.ddram[lcd.ddPtr] = data
+	}
+	lcd.doShift()
+}
+
+// ReadData reads data from DDRAM or CGRAM, i.e. reads with RS high.
+func (lcd *Lcd) ReadData() byte {
+	var data byte
+	if lcd.cgActive {
+		row := lcd.cgPtr % charHeight
+		start := lcd.cgPtr - row
+		for i := 0; i < charWidth; i++ {
+			if lcd.cgram[start+i]&(0x80>>row) != 0 {
+				data |= (1 << (charWidth - 1)) >> i
+			}
+		}
+	} else {
+		data = lcd.ddram[lcd.ddPtr]
+	}
+	lcd.doShift()
+	return data
+}
+
+// ReadAddress returns the address counter, i.e. what a read with RS low and R/W high returns
+// apart from the busy flag.
+func (lcd *Lcd) ReadAddress() byte {
+	if lcd.cgActive {
+		return byte(lcd.cgPtr & 0x3f)
+	}
+	return byte(lcd.ddPtr & 0x7f)
+}
+
+// WriteString writes each byte of str in turn.
+func (lcd *Lcd) WriteString(str string) {
+	for i := 0; i < len(str); i++ {
+		lcd.WriteData(str[i])
+	}
+}
+
+// CharBits returns the 5 column bytes of character c, which is in CGRAM if it's less than 16.
+func (lcd *Lcd) CharBits(c byte) []byte {
+	if c < cgramChars {
+		return lcd.cgram[int(c)*charHeight : int(c)*charHeight+charWidth]
+	}
+	if lcd.rom == RomA00 {
+		return fontA00[c-cgramChars][:]
+	}
+	return fontA02[c-cgramChars][:]
+}
+
+// DataOffset returns the DDRAM address shown at row and col, given the display shift.
+func (lcd *Lcd) DataOffset(row, col int) int {
+	if row >= lcd.Rows {
+		row = lcd.Rows - 1
+	}
+	for lcd.scrollOffset < 0 {
+		lcd.scrollOffset += lcd.dataWidth
+	}
+	dataCol := (col + lcd.scrollOffset) % lcd.dataWidth
+	rowOffset := row * lcd.dataWidth
+	if lcd.Rows > 2 {
+		rowOffset = rowOffsets[row]
+	}
+	return rowOffset + dataCol
+}
+
+// RowAddress returns the DDRAM address of the first character of row, when the display isn't
+// shifted.
+func (lcd *Lcd) RowAddress(row int) int {
+	return rowOffsets[row]
+}
+
+// DisplayRam returns the DDRAM. It's the LCD's own memory, not a copy.
+func (lcd *Lcd) DisplayRam() []byte {
+	return lcd.ddram[:]
+}
+
+// CursorOffset returns the DDRAM address of the cursor.
+func (lcd *Lcd) CursorOffset() int {
+	return lcd.ddPtr
+}
+
+// DisplayOn returns whether the display is on, as set by the display control instruction.
+func (lcd *Lcd) DisplayOn() bool {
+	return lcd.displayFlags&CmdDisplayOn != 0
+}
+
+// CursorStyle returns whether the cursor is shown as an underline, and whether it blinks.
+func (lcd *Lcd) CursorStyle() (underline, blink bool) {
+	return lcd.displayFlags&CmdDisplayCursor != 0, lcd.displayFlags&CmdDisplayCursorBlink != 0
+}
+
+// UpdatePixels renders the display to its pixels, which change only when this is called.
+func (lcd *Lcd) UpdatePixels() {
+	blinkShowing := lcd.Clock().UnixNano()/int64(cursorBlinkPeriod)%2 == 1
+	lcd.Render(blinkShowing)
+}
+
+// Render renders the display to its pixels, with the blinking cursor in the given phase.
+func (lcd *Lcd) Render(blinkShowing bool) {
+	cursorOn := lcd.displayFlags & cursorMask
+	if !blinkShowing {
+		cursorOn &^= CmdDisplayCursorBlink
+	}
+	displayOn := lcd.DisplayOn()
+
+	for row := 0; row < lcd.Rows; row++ {
+		for col := 0; col < lcd.Cols; col++ {
+			topLeft := row*(charHeight+1)*lcd.pixelsWidth + col*(charWidth+1)
+			offset := lcd.DataOffset(row, col)
+			c := lcd.ddram[offset]
+			drawCursor := cursorOn != 0 && offset == lcd.ddPtr
+			bits := lcd.CharBits(c)
+
+			// The ROM fonts are defined wrong, shifted one pixel too low, so are shifted back up.
+			glyphShift := 0
+			if c >= cgramChars {
+				glyphShift = 1
+			}
+
+			for y := 0; y < charHeight; y++ {
+				for x := 0; x < charWidth; x++ {
+					pixel := &lcd.pixels[topLeft+y*lcd.pixelsWidth+x]
+					if !displayOn {
+						*pixel = 0
+						continue
+					}
+					*pixel = 0
+					if (int(bits[x])<<glyphShift)&(0x80>>y) != 0 {
+						*pixel = 1
+					}
+					if drawCursor {
+						if cursorOn&CmdDisplayCursorBlink != 0 ||
+							(cursorOn&CmdDisplayCursor != 0 && y == charHeight-1) {
+							*pixel = 1
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// NumPixels returns the size of the display in pixels, including the gaps between characters.
+func (lcd *Lcd) NumPixels() (width, height int) {
+	return lcd.pixelsWidth, lcd.pixelsHeight
+}
+
+// PixelState returns 1 if the pixel at x, y is on, 0 if it's off, and -1 if there's no pixel
+// there, i.e. it's in a gap between characters or off the display.
+func (lcd *Lcd) PixelState(x, y int) int {
+	offset := y*lcd.pixelsWidth + x
+	if offset >= 0 && offset < len(lcd.pixels) {
+		return int(lcd.pixels[offset])
+	}
+	return -1
+}
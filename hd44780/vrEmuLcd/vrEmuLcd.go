@@ -0,0 +1,69 @@
+// Package vrEmuLcd wraps the C vrEmuLcd that hd44780 was ported from, as the reference that
+// hd44780's conformance test compares it against. The app itself doesn't use it.
+package vrEmuLcd
+
+// /* The header declares the LCD_CMD_* constants without extern. */
+// #cgo CFLAGS: -fcommon
+// #include "vrEmuLcd.h"
+import "C"
+
+import "unsafe"
+
+const ddramSize = 0x80
+
+type Lcd struct {
+	lcd *C.VrEmuLcd
+}
+
+// New returns an Lcd of cols x rows characters, using ROM A00 if a00 is true and A02 otherwise.
+func New(cols, rows int, a00 bool) *Lcd {
+	rom := C.vrEmuLcdCharacterRom(C.EmuLcdRomA02)
+	if a00 {
+		rom = C.EmuLcdRomA00
+	}
+	return &Lcd{C.vrEmuLcdNew(C.int(cols), C.int(rows), rom)}
+}
+
+func (l *Lcd) Destroy() {
+	C.vrEmuLcdDestroy(l.lcd)
+	l.lcd = nil
+}
+
+func (l *Lcd) SendCommand(command byte) {
+	C.vrEmuLcdSendCommand(l.lcd, C.byte(command))
+}
+
+func (l *Lcd) WriteData(data byte) {
+	C.vrEmuLcdWriteByte(l.lcd, C.byte(data))
+}
+
+func (l *Lcd) ReadData() byte {
+	return byte(C.vrEmuLcdReadByte(l.lcd))
+}
+
+func (l *Lcd) ReadAddress() byte {
+	return byte(C.vrEmuLcdReadAddress(l.lcd))
+}
+
+// DisplayRam returns a copy of the DDRAM.
+func (l *Lcd) DisplayRam() []byte {
+	return C.GoBytes(unsafe.Pointer(C.vrEmuLcdGetDisplayRam(l.lcd)), ddramSize)
+}
+
+func (l *Lcd) CursorOffset() int {
+	return int(C.vrEmuLcdGetCursorOffset(l.lcd))
+}
+
+func (l *Lcd) UpdatePixels() {
+	C.vrEmuLcdUpdatePixels(l.lcd)
+}
+
+func (l *Lcd) NumPixels() (width, height int) {
+	var cWidth, cHeight C.int
+	C.vrEmuLcdNumPixels(l.lcd, &cWidth, &cHeight)
+	return int(cWidth), int(cHeight)
+}
+
+func (l *Lcd) PixelState(x, y int) int {
+	return int(C.vrEmuLcdPixelState(l.lcd, C.int(x), C.int(y)))
+}
@@ -5,6 +5,7 @@ package pty
 
 /*
  #define _XOPEN_SOURCE 600
+ #define _DEFAULT_SOURCE // For cfmakeraw
  #include <fcntl.h>
  #include <stdlib.h>
  #include <unistd.h>
@@ -61,3 +62,17 @@ func Open() (pty *os.File, processTTY string, err error) {
 	processTTY = C.GoString(C.ptsname(m))
 	return os.NewFile(uintptr(m), "pty"), processTTY, nil
 }
+
+// MakeRaw puts the pty in raw mode, so that binary data passes through it unchanged and isn't echoed.
+func MakeRaw(pty *os.File) error {
+	var tio C.struct_termios
+	fd := C.int(pty.Fd())
+	if _, err := C.tcgetattr(fd, &tio); err != nil {
+		return ptyError("tcgetattr", err)
+	}
+	C.cfmakeraw(&tio)
+	if _, err := C.tcsetattr(fd, C.TCSANOW, &tio); err != nil {
+		return ptyError("tcsetattr", err)
+	}
+	return nil
+}
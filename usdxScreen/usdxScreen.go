@@ -0,0 +1,213 @@
+// Package usdxScreen recognizes what the uSDX is showing on its 16 x 2 LCD, from the characters
+// in a settled frame, so that the rest of the app can work with the screen's meaning rather than
+// with the positions of characters on it.
+package usdxScreen
+
+import (
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+	"uSDX/ambEmuLcd"
+)
+
+// A Screen is one of Blank, Splash, Main, MenuList, MenuEdit or Message.
+type Screen interface {
+	fmt.Stringer
+	screen()
+}
+
+// Blank is an empty display, as after a power down.
+type Blank struct{}
+
+// Splash is the screen that the firmware shows as it starts, with its name and version.
+type Splash struct {
+	Product string // E.g. "QCX-SSB"
+	Version string // E.g. "R1.02w"
+}
+
+// Main is the main screen, with the VFO's frequency and mode.
+type Main struct {
+	Vfo         string // "A" or "B"
+	FrequencyHz int64
+	Mode        string // E.g. "USB", without padding.
+	SMeter      int    // S units, as shown by the s-meter bars.
+	Line1Text   string // Whatever's to the left of the s-meter, e.g. CW decoder output, trimmed.
+	StepHz      int64  // The step of the digit under the cursor, or 0 if it isn't on a digit.
+}
+
+// MenuList is the menu, browsing from item to item.
+type MenuList struct {
+	Number string // E.g. "1.2"
+	Name   string
+	Value  string
+}
+
+// MenuEdit is the menu, with the item's value being edited.
+type MenuEdit struct {
+	Number string
+	Name   string
+	Value  string
+}
+
+// Message is any other screen, such as the transient messages that the firmware shows.
+type Message struct {
+	Line1, Line2 string // Trimmed
+}
+
+func (Blank) screen()    {}
+func (Splash) screen()   {}
+func (Main) screen()     {}
+func (MenuList) screen() {}
+func (MenuEdit) screen() {}
+func (Message) screen()  {}
+
+func (Blank) String() string {
+	return "blank"
+}
+
+func (s Splash) String() string {
+	return fmt.Sprintf("splash %s %s", s.Product, s.Version)
+}
+
+func (m Main) String() string {
+	return fmt.Sprintf("main %s %d %s S%d step %d %q", m.Vfo, m.FrequencyHz, m.Mode, m.SMeter, m.StepHz, m.Line1Text)
+}
+
+func (m MenuList) String() string {
+	return fmt.Sprintf("menu %s %q = %q", m.Number, m.Name, m.Value)
+}
+
+func (m MenuEdit) String() string {
+	return fmt.Sprintf("edit %s %q = %q", m.Number, m.Name, m.Value)
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("message %q %q", m.Line1, m.Line2)
+}
+
+// ParseSettled recognizes the screen in a Settled event.
+func ParseSettled(e *ambEmuLcd.Settled) Screen {
+	return Parse(e.Line1Data, e.Line2Data, e.CursorPos)
+}
+
+// Parse recognizes the screen that shows line1 and line2, with the cursor at cursor, which is a
+// column and a row counting from 1, as in ambEmuLcd.Settled.
+func Parse(line1, line2 []byte, cursor image.Point) Screen {
+	if len(line1) < cols || len(line2) < cols {
+		return Message{strings.TrimSpace(string(line1)), strings.TrimSpace(string(line2))}
+	}
+	if main, ok := parseMain(line1, line2, cursor); ok {
+		return main
+	}
+	text1, text2 := strings.TrimSpace(string(line1)), strings.TrimSpace(string(line2))
+	switch {
+	case text1 == "" && text2 == "":
+		return Blank{}
+	case splashPattern.MatchString(text1) && versionPattern.MatchString(text2):
+		fields := versionPattern.FindStringSubmatch(text2)
+		return Splash{Product: fields[1], Version: fields[2]}
+	case menuPattern.MatchString(text1):
+		fields := menuPattern.FindStringSubmatch(text1)
+		if line2[0] == editMarker {
+			return MenuEdit{Number: fields[1], Name: fields[2], Value: strings.TrimSpace(string(line2[1:]))}
+		}
+		return MenuList{Number: fields[1], Name: fields[2], Value: text2}
+	}
+	return Message{text1, text2}
+}
+
+const cols = 16
+
+var (
+	splashPattern  = regexp.MustCompile(`^uSDX`)
+	versionPattern = regexp.MustCompile(`^(\S+) (R\d+\.\d+\w*)$`)
+	menuPattern    = regexp.MustCompile(`^(\d+\.\d+) (.+)$`)
+)
+
+// The firmware marks the value being edited with a '>' at the start of the second line.
+const editMarker = '>'
+
+// The main screen's second line is the VFO glyph, the frequency in tens of Hz as "dd,ddd,dd",
+// with leading zeros shown as spaces, then the mode at columns 11 to 13. The first line ends with
+// the s-meter.
+const (
+	vfoAGlyph = 6
+	vfoBGlyph = 7
+	freqCol   = 1
+	freqCols  = 9
+	modeCol   = 11
+	sMeterCol = 12
+)
+
+var modeLabels = []string{"LSB", "USB", "CW ", "FM ", "AM "}
+
+func parseMain(line1, line2 []byte, cursor image.Point) (Main, bool) {
+	var m Main
+	switch line2[0] {
+	case vfoAGlyph:
+		m.Vfo = "A"
+	case vfoBGlyph:
+		m.Vfo = "B"
+	default:
+		return m, false
+	}
+
+	freq := string(line2[freqCol : freqCol+freqCols])
+	if freq[2] != ',' || freq[6] != ',' {
+		return m, false
+	}
+	daHz, err := strconv.ParseInt(strings.TrimSpace(strings.ReplaceAll(freq, ",", "")), 10, 64)
+	if err != nil {
+		return m, false
+	}
+	m.FrequencyHz = daHz * 10 // The uSDX doesn't display the ones.
+
+	label := string(line2[modeCol : modeCol+3])
+	for _, l := range modeLabels {
+		if label == l {
+			m.Mode = strings.TrimSpace(l)
+		}
+	}
+	if m.Mode == "" {
+		return m, false
+	}
+
+	m.SMeter = sMeter(line1[sMeterCol:cols])
+	m.Line1Text = strings.TrimSpace(string(line1[:sMeterCol]))
+	if cursor.Y == 2 {
+		m.StepHz = stepHz(cursor.X)
+	}
+	return m, true
+}
+
+// The s-meter is four bar glyphs (custom characters 2 to 5), each showing 0 to 3 bars, and the
+// firmware lights one bar per S unit above S1.
+func sMeter(glyphs []byte) int {
+	bars := 0
+	for _, c := range glyphs {
+		if c >= 2 && c <= 5 {
+			bars += int(c - 2)
+		}
+	}
+	if bars == 0 {
+		return 0
+	}
+	return bars + 1
+}
+
+// stepHz returns the step of the frequency digit at col, or 0 if col isn't a digit.
+func stepHz(col int) int64 {
+	i := col - freqCol
+	if i < 0 || i >= freqCols || i == 2 || i == 6 {
+		return 0
+	}
+	step := int64(10)
+	for j := freqCols - 1; j > i; j-- {
+		if j != 2 && j != 6 {
+			step *= 10
+		}
+	}
+	return step
+}
@@ -0,0 +1,89 @@
+package usdxScreen
+
+import (
+	"image"
+	"testing"
+)
+
+// Frames of each kind of screen, with the screens that they should be recognized as. Each is
+// labelled with the firmware version that it was captured from. Those labelled "unconfirmed"
+// weren't captured: they're laid out as the parser and usdxSim expect, which hasn't been checked
+// against a radio, so they only guard the parser against regressions. Frames captured from a
+// radio, e.g. the Settled screens of an -lcd-capture, belong here too, labelled with the version
+// on its splash screen, so that a difference in layout shows up as a failing test.
+
+// A fixture is a settled frame and its Screen, as a string.
+type fixture struct {
+	firmware     string
+	name         string
+	line1, line2 string
+	cursor       image.Point
+	want         string
+}
+
+// The firmware label of frames that weren't captured from a radio.
+const unconfirmed = "unconfirmed"
+
+// The s-meter glyphs, from no bars to three.
+const (
+	s0 = "\x02"
+	s1 = "\x03"
+	s2 = "\x04"
+	s3 = "\x05"
+)
+
+var fixtures = []fixture{
+	{unconfirmed, "blank", "                ", "                ", image.Pt(0, 1),
+		"blank"},
+	{unconfirmed, "splash", "uSDX            ", "QCX-SSB R1.02w  ", image.Pt(0, 1),
+		"splash QCX-SSB R1.02w"},
+
+	{unconfirmed, "main", "            " + s0 + s0 + s0 + s0, "\x06 7,074,00 USB  ", image.Pt(6, 2),
+		`main A 7074000 USB S0 step 1000 ""`},
+	{unconfirmed, "main VFO B", "            " + s3 + s1 + s0 + s0, "\x0714,074,00 USB  ", image.Pt(1, 2),
+		`main B 14074000 USB S5 step 10000000 ""`},
+	{unconfirmed, "main LSB", "            " + s3 + s3 + s3 + s2, "\x06 3,573,00 LSB  ", image.Pt(9, 2),
+		`main A 3573000 LSB S12 step 10 ""`},
+	{unconfirmed, "main CW with decoder text", "CQ CQ DE PA0  " + s2 + s0, "\x06 7,030,00 CW   ", image.Pt(6, 2),
+		`main A 7030000 CW S3 step 1000 "CQ CQ DE PA0"`},
+	{unconfirmed, "main cursor on comma", "            " + s0 + s0 + s0 + s0, "\x06 7,074,00 AM   ", image.Pt(3, 2),
+		`main A 7074000 AM S0 step 0 ""`},
+	{unconfirmed, "main below 1 MHz", "            " + s0 + s0 + s0 + s0, "\x06  ,475,00 CW   ", image.Pt(8, 2),
+		`main A 475000 CW S0 step 100 ""`},
+
+	{unconfirmed, "menu list", "1.2 Mode        ", " USB            ", image.Pt(0, 1),
+		`menu 1.2 "Mode" = "USB"`},
+	{unconfirmed, "menu list two digit item", "1.10 ATT2       ", " 0              ", image.Pt(0, 1),
+		`menu 1.10 "ATT2" = "0"`},
+	{unconfirmed, "menu edit", "2.4 Keyer speed ", ">20             ", image.Pt(1, 2),
+		`edit 2.4 "Keyer speed" = "20"`},
+
+	{unconfirmed, "message", "   Out of band  ", "                ", image.Pt(0, 1),
+		`message "Out of band" ""`},
+	{unconfirmed, "main screen garbled", "                ", "\x06 7,0x4,00 USB  ", image.Pt(0, 1),
+		`message "" "\x06 7,0x4,00 USB"`},
+}
+
+func TestParse(t *testing.T) {
+	for _, f := range fixtures {
+		t.Run(f.firmware+" "+f.name, func(t *testing.T) {
+			if got := Parse([]byte(f.line1), []byte(f.line2), f.cursor).String(); got != f.want {
+				t.Errorf("got %s, want %s", got, f.want)
+			}
+		})
+	}
+}
+
+// TestFramesFromRadio reports, as a skip, that none of the fixtures were captured from a radio.
+// Until they are, a layout that differs from the firmware's won't fail TestParse. To add them,
+// capture each kind of screen with the app's -lcd-capture, put the capture in ambEmuLcd's
+// testdata/captures, named after the version on the splash screen, run ambEmuLcd's tests with
+// -update to write its Settled screens, and copy those here, labelled with that version.
+func TestFramesFromRadio(t *testing.T) {
+	for _, f := range fixtures {
+		if f.firmware != unconfirmed {
+			return
+		}
+	}
+	t.Skip("no fixtures captured from a radio")
+}
@@ -0,0 +1,355 @@
+package usdxSim_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"uSDX/ambEmuLcd"
+	"uSDX/controls"
+	"uSDX/hd44780"
+	"uSDX/usdxSim"
+)
+
+const timeout = 30 * time.Second
+
+// How long the app must leave the radio alone for an operation to be taken as finished.
+const quiet = time.Second
+
+var ctx = context.Background()
+
+// An e2e is a test of the app's high level controls, driving a simulated uSDX.
+type e2e struct {
+	*testing.T
+	sim *usdxSim.Sim
+}
+
+// TestEndToEnd drives the app's LCD decoder, high level controls and CAT server against a
+// simulated uSDX, and checks that the simulated radio ends up as they asked. The simulator draws
+// its screens as the app expects them, so this checks the app's logic, not its reading of the
+// real firmware's screens. It takes about a minute, so it's skipped by go test -short.
+func TestEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes about a minute")
+	}
+	sim, port := usdxSim.Loopback()
+
+	decoder := ambEmuLcd.NewDecoder(16, 2, hd44780.RomA02)
+	go decoder.ProcessSerialLcdData(port)
+	go func() {
+		for e := range decoder.Events {
+			switch e := e.(type) {
+			case *ambEmuLcd.Settled:
+				controls.HandleSettledEvent(e)
+			case ambEmuLcd.PoweredOn:
+				controls.HandlePowerEvent(true)
+			case ambEmuLcd.PoweredOff:
+				controls.HandlePowerEvent(false)
+			}
+		}
+	}()
+	controls.InitLowLevelControls(port)
+	cacheDir, err := ioutil.TempDir("", "e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	menuCache := filepath.Join(cacheDir, "menu.json")
+	controls.InitHighLevelControls(menuCache)
+
+	catAddr := freeAddr(t)
+	endpoints, err := controls.ParseCatEndpoints("tcp:" + catAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls.ProcessCatEndpoints(endpoints)
+
+	sim.PowerOn()
+
+	cat := dialCat(t, catAddr)
+	e := &e2e{t, sim}
+	e.check("main screen is read", func() bool { return cat.ask("FA;") == "FA00007074000;" })
+
+	// The splash screen shows firmware that isn't in the cache, so the menu is walked.
+	e.check("menu is discovered", func() bool { return len(controls.MenuItems()) == 20 && sim.Quiet(quiet) })
+	e.check("menu items are recorded", func() bool {
+		items := controls.MenuItems()
+		return controls.MenuFirmware() == usdxSim.SplashLine2 &&
+			items[1] == controls.MenuItem{Index: 1, Number: "1.2", Name: "Mode", Value: "USB", EditType: "choice"} &&
+			items[19] == controls.MenuItem{Index: 19, Number: "3.5", Name: "MOX", Value: "OFF", EditType: "choice"} &&
+			items[12] == controls.MenuItem{Index: 12, Number: "2.2", Name: "CW Tone", Value: "650", EditType: "choice"} &&
+			items[0] == controls.MenuItem{Index: 0, Number: "1.1", Name: "Volume", Value: "12", EditType: "number"} &&
+			items[14] == controls.MenuItem{Index: 14, Number: "2.4", Name: "Keyer speed", Value: "20", EditType: "number"}
+	})
+	controls.InitHighLevelControls(menuCache)
+	e.check("menu is loaded from the cache", func() bool {
+		return len(controls.MenuItems()) == 20 && controls.MenuFirmware() == usdxSim.SplashLine2
+	})
+	powerCycled := time.Now()
+	sim.PowerOff()
+	sim.PowerOn()
+	time.Sleep(5 * time.Second)
+	e.check("menu isn't walked again for the same firmware", func() bool { return sim.Quiet(time.Since(powerCycled)) })
+
+	e.checkSetSetting("Keyer speed", "25")
+	e.checkSetSetting("Noise Gate", "1")
+	e.checkSetSetting("ATT", "-20dB")
+	e.checkSetSetting("Filter BW", "500")
+	e.checkSetSetting("Filter BW", "3000") // A choice, though it looks like a number above 500.
+	e.checkSetSettingFails("Volume", "99")
+	e.checkSetSettingFails("Squelch", "1")
+
+	e.checkCancelSetting("Noise Gate", "200")
+
+	profile, err := controls.TakeProfile(ctx, "e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.checkSetSetting("Volume", "8")
+	e.checkSetSetting("CW Tone", "325")
+	diffs, err := controls.DiffProfile(ctx, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.check("DiffProfile reports the changed settings", func() bool {
+		return fmt.Sprint(diffs) == "[Volume: 8 -> 12 CW Tone: 325 -> 650]"
+	})
+	other := *profile
+	other.Firmware = "QCX-SSB R1.00"
+	err = controls.RestoreProfile(ctx, &other, false, func(string) bool { return true })
+	e.check("RestoreProfile refuses other firmware's profile", func() bool {
+		volume, _ := sim.Setting("Volume")
+		return err != nil && volume == "8"
+	})
+	var report string
+	err = controls.RestoreProfile(ctx, profile, false, func(r string) bool { report = r; return false })
+	e.check("RestoreProfile gives the report, and can be declined", func() bool {
+		volume, _ := sim.Setting("Volume")
+		return err != nil && strings.Contains(report, "2 differences") && volume == "8"
+	})
+	var volumeAtReview string
+	err = controls.RestoreProfile(ctx, profile, false, func(string) bool {
+		volumeAtReview, _ = sim.Setting("Volume")
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.check("RestoreProfile restores the changed settings", func() bool {
+		volume, _ := sim.Setting("Volume")
+		tone, _ := sim.Setting("CW Tone")
+		return volume == "12" && tone == "650" && volumeAtReview == "8" && sim.Quiet(quiet)
+	})
+
+	controls.SetFrequency("00014074000")
+	e.check("SetFrequency tunes the radio", func() bool { return sim.Frequency() == 14074000 && sim.Quiet(quiet) })
+	e.check("CAT reads the new frequency", func() bool { return cat.ask("FA;") == "FA00014074000;" })
+
+	if err := controls.SetFrequencyContext(ctx, 21074000); err != nil {
+		t.Fatal(err)
+	}
+	e.check("SetFrequencyContext tunes the radio", func() bool { return sim.Frequency() == 21074000 })
+	cancelled, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	err = controls.SetFrequencyContext(cancelled, 1840000)
+	cancel()
+	e.check(fmt.Sprintf("SetFrequencyContext stops when cancelled: %v", err), func() bool {
+		return err == context.DeadlineExceeded && sim.Frequency() != 1840000 && sim.OnMainScreen() && sim.Quiet(quiet)
+	})
+
+	for _, hz := range []int64{21100000, 21150000, 21200000, 21250000, 21300000} {
+		controls.SetFrequency(fmt.Sprintf("%011d", hz))
+	}
+	status := controls.Operations()
+	e.check(fmt.Sprintf("a burst of SetFrequency is coalesced: %v", status), func() bool { return status.Depth() <= 2 })
+	e.check("the burst tunes the radio to its last frequency", func() bool {
+		return sim.Frequency() == 21300000 && controls.Operations().Depth() == 0 && sim.Quiet(quiet)
+	})
+	e.checkConcurrentOperations()
+	e.checkConcurrentSettings()
+	e.check("CAT KS reads the menu's Keyer speed", func() bool { return cat.ask("KS;") == "KS024;" })
+	e.check("CAT FW reads the menu's Filter BW", func() bool { return cat.ask("FW;") == "FW3000;" })
+	cat.tell("KS026;")
+	e.check("CAT KS sets the Keyer speed", func() bool {
+		speed, _ := sim.Setting("Keyer speed")
+		return speed == "26" && cat.ask("KS;") == "KS026;" && sim.Quiet(quiet)
+	})
+
+	cat.tell("FA00003573000;")
+	e.check("CAT FA tunes the radio", func() bool { return sim.Frequency() == 3573000 && sim.Quiet(quiet) })
+	controls.RotateEncoderClockwise() // A turn of the knob, by hand.
+	e.check("the knob retunes the radio", func() bool { return sim.Frequency() != 3573000 && sim.Quiet(quiet) })
+	cat.tell("FA00003573000;")
+	e.check("CAT FA tunes the radio back", func() bool { return sim.Frequency() == 3573000 && sim.Quiet(quiet) })
+
+	cat.tell("MD3;")
+	e.check("CAT MD sets the mode", func() bool { return sim.Mode() == "CW" && sim.Quiet(quiet) })
+	e.check("CAT reads the new mode", func() bool { return cat.ask("MD;") == "MD3;" })
+
+	cat.tell("TX;")
+	e.check("CAT TX starts transmitting", func() bool { return sim.Transmitting() })
+	e.check("CAT IF reports transmitting", func() bool { r := cat.ask("IF;"); return len(r) == 38 && r[28] == '1' })
+	cat.tell("RX;")
+	e.check("CAT RX stops transmitting", func() bool { return !sim.Transmitting() })
+
+	sim.PowerOff()
+	e.check("CAT PS reports the radio off", func() bool { return cat.ask("PS;") == "PS0;" })
+	cat.tell("FA00007030000;")
+	time.Sleep(quiet)
+	e.check("CAT FA waits while the radio is off", func() bool { return sim.Frequency() == 3573000 })
+	sim.PowerOn()
+	e.check("CAT PS reports the radio on", func() bool { return cat.ask("PS;") == "PS1;" })
+	e.check("CAT FA tunes the radio once it's on", func() bool { return sim.Frequency() == 7030000 && sim.Quiet(quiet) })
+
+}
+
+func (e *e2e) check(desc string, ok func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !ok() {
+		if time.Now().After(deadline) {
+			e.Fatalf("%s: timed out", desc)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	e.Log(desc)
+}
+
+// checkSetSetting sets a setting, and checks that the radio and the menu map have its value.
+func (e *e2e) checkSetSetting(name, value string) {
+	desc := fmt.Sprintf("SetSetting sets %s to %s", name, value)
+	if err := controls.SetSetting(ctx, name, value); err != nil {
+		e.Fatalf("%s: %v", desc, err)
+	}
+	e.check(desc, func() bool {
+		simValue, _ := e.sim.Setting(name)
+		for _, item := range controls.MenuItems() {
+			if item.Name == name {
+				return simValue == value && item.Value == value
+			}
+		}
+		return false
+	})
+}
+
+// checkSetSettingFails tries to set a setting, and checks that it's left as it was.
+func (e *e2e) checkSetSettingFails(name, value string) {
+	before, _ := e.sim.Setting(name)
+	err := controls.SetSetting(ctx, name, value)
+	if err == nil {
+		e.Fatalf("SetSetting %s to %s succeeded", name, value)
+	}
+	e.check(fmt.Sprintf("SetSetting fails to set %s to %s: %v", name, value, err), func() bool {
+		after, _ := e.sim.Setting(name)
+		return after == before && e.sim.Quiet(quiet)
+	})
+}
+
+// checkConcurrentOperations sets the mode and a setting at the same time, and checks that both
+// are carried out, one after the other.
+func (e *e2e) checkConcurrentOperations() {
+	modeErr := make(chan error)
+	go func() { modeErr <- controls.SetModeContext(ctx, 1) }()
+	settingErr := controls.SetSetting(ctx, "Keyer speed", "22")
+	if err := <-modeErr; err != nil {
+		e.Fatalf("SetModeContext alongside SetSetting: %v", err)
+	}
+	if settingErr != nil {
+		e.Fatalf("SetSetting alongside SetModeContext: %v", settingErr)
+	}
+	e.check("concurrent operations are both carried out", func() bool {
+		speed, _ := e.sim.Setting("Keyer speed")
+		return e.sim.Mode() == "LSB" && speed == "22" && e.sim.OnMainScreen() && e.sim.Quiet(quiet)
+	})
+}
+
+// checkConcurrentSettings sets a setting to two values at the same time, and checks that both
+// edits are carried out, in turn, and that the menu map has the last.
+func (e *e2e) checkConcurrentSettings() {
+	firstErr := make(chan error)
+	go func() { firstErr <- controls.SetSetting(ctx, "Keyer speed", "23") }()
+	for controls.Operations().Depth() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := controls.SetSetting(ctx, "Keyer speed", "24"); err != nil {
+		e.Fatalf("SetSetting alongside another: %v", err)
+	}
+	if err := <-firstErr; err != nil {
+		e.Fatalf("SetSetting alongside another: %v", err)
+	}
+	e.check("concurrent settings are both carried out", func() bool {
+		speed, _ := e.sim.Setting("Keyer speed")
+		for _, item := range controls.MenuItems() {
+			if item.Name == "Keyer speed" {
+				return speed == "24" && item.Value == "24" && e.sim.Quiet(quiet)
+			}
+		}
+		return false
+	})
+}
+
+// checkCancelSetting starts setting a setting that takes many steps, cancels it part way, and
+// checks that the setting is left as it was, with the radio back on its main screen.
+func (e *e2e) checkCancelSetting(name, value string) {
+	before, _ := e.sim.Setting(name)
+	cancelled, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := controls.SetSetting(cancelled, name, value)
+	if err == nil {
+		e.Fatalf("SetSetting %s to %s wasn't cancelled", name, value)
+	}
+	e.check(fmt.Sprintf("SetSetting recovers when cancelled: %v", err), func() bool {
+		after, _ := e.sim.Setting(name)
+		return after == before && e.sim.OnMainScreen() && e.sim.Quiet(quiet)
+	})
+}
+
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+type catConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialCat(t *testing.T, addr string) *catConn {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			return &catConn{t, conn, bufio.NewReader(conn)}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// tell sends a command that has no response.
+func (c *catConn) tell(cmd string) {
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// ask sends a command and returns its response.
+func (c *catConn) ask(cmd string) string {
+	c.tell(cmd)
+	response, err := c.reader.ReadString(';')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return response
+}
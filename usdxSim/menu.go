@@ -0,0 +1,74 @@
+package usdxSim
+
+import "strconv"
+
+// A setting is one item on the uSDX's menu. Its value is either one of choices, or a number
+// from min to max.
+type setting struct {
+	id       string // E.g. "1.1", as shown before the name
+	name     string
+	choices  []string
+	min, max int
+	value    int // The number, or the index of the choice
+}
+
+func (s *setting) display() string {
+	if s.choices != nil {
+		return s.choices[s.value]
+	}
+	return strconv.Itoa(s.value)
+}
+
+// adjust moves the value one step in dir, stopping at either end, as the encoder does.
+func (s *setting) adjust(dir int) {
+	min, max := s.min, s.max
+	if s.choices != nil {
+		min, max = 0, len(s.choices)-1
+	}
+	s.value += dir
+	if s.value < min {
+		s.value = min
+	} else if s.value > max {
+		s.value = max
+	}
+}
+
+// Indexes of the settings that the main screen shows.
+const (
+	modeSetting    = 1
+	vfoModeSetting = 4
+)
+
+// The mode labels, in the order that the right button steps through them.
+var modeLabels = []string{"LSB", "USB", "CW ", "FM ", "AM "}
+
+const (
+	vfoModeA = 0
+	vfoModeB = 1
+)
+
+// newMenu returns the simulator's menu, a subset of the uSDX firmware's with its defaults.
+func newMenu() []setting {
+	return []setting{
+		{id: "1.1", name: "Volume", min: -1, max: 16, value: 12},
+		{id: "1.2", name: "Mode", choices: []string{"LSB", "USB", "CW", "FM", "AM"}, value: 1},
+		{id: "1.3", name: "Filter BW", choices: []string{"Full", "3000", "2400", "1800", "500", "200", "100", "50"}},
+		{id: "1.4", name: "Band", choices: []string{"160m", "80m", "60m", "40m", "30m", "20m", "17m", "15m", "12m", "10m", "6m"}, value: 3},
+		{id: "1.5", name: "VFO Mode", choices: []string{"A", "B"}},
+		{id: "1.6", name: "RIT", choices: []string{"OFF", "ON"}},
+		{id: "1.7", name: "AGC", choices: []string{"OFF", "ON"}, value: 1},
+		{id: "1.8", name: "NR", min: 0, max: 8},
+		{id: "1.9", name: "ATT", choices: []string{"0dB", "-13dB", "-20dB", "-33dB", "-40dB", "-53dB", "-60dB", "-73dB"}},
+		{id: "1.10", name: "ATT2", min: 0, max: 16},
+		{id: "1.11", name: "S-meter", choices: []string{"OFF", "dBm", "S", "S-bar", "wpm", "Vss"}, value: 3},
+		{id: "2.1", name: "CW Decoder", choices: []string{"OFF", "ON"}},
+		{id: "2.2", name: "CW Tone", choices: []string{"325", "650"}, value: 1},
+		{id: "2.3", name: "Semi QSK", choices: []string{"OFF", "ON"}},
+		{id: "2.4", name: "Keyer speed", min: 1, max: 60, value: 20},
+		{id: "3.1", name: "VOX", choices: []string{"OFF", "ON"}},
+		{id: "3.2", name: "Noise Gate", min: 0, max: 255, value: 4},
+		{id: "3.3", name: "TX Drive", min: 0, max: 8, value: 4},
+		{id: "3.4", name: "TX Delay", min: 0, max: 255, value: 0},
+		{id: "3.5", name: "MOX", choices: []string{"OFF", "ON"}},
+	}
+}
@@ -0,0 +1,103 @@
+package usdxSim
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"uSDX/pty"
+)
+
+// ReadTimeout is how long a Port's Read waits for LCD data, like the app's serial port.
+const ReadTimeout = 50 * time.Millisecond
+
+// A Port connects the app to a Sim in the same process, in place of the controller board's
+// serial port. Writes go to the Sim as control bytes, and reads return its LCD writes, or
+// nothing if there are none within ReadTimeout.
+type Port struct {
+	sim   *Sim
+	mutex sync.Mutex
+	data  bytes.Buffer
+	ready chan bool // Signalled when data arrives
+}
+
+// Loopback returns a new Sim, powered off, connected to a Port.
+func Loopback() (*Sim, *Port) {
+	port := &Port{ready: make(chan bool, 1)}
+	port.sim = New(portWriter{port})
+	return port.sim, port
+}
+
+func (p *Port) Read(buf []byte) (int, error) {
+	p.mutex.Lock()
+	if p.data.Len() == 0 {
+		p.mutex.Unlock()
+		select {
+		case <-p.ready:
+		case <-time.After(ReadTimeout):
+			return 0, nil
+		}
+		p.mutex.Lock()
+	}
+	defer p.mutex.Unlock()
+	return p.data.Read(buf)
+}
+
+func (p *Port) Write(controls []byte) (int, error) {
+	return p.sim.Write(controls)
+}
+
+// portWriter takes the Sim's LCD writes for its Port.
+type portWriter struct {
+	port *Port
+}
+
+func (w portWriter) Write(lcdData []byte) (int, error) {
+	w.port.mutex.Lock()
+	defer w.port.mutex.Unlock()
+	w.port.data.Write(lcdData)
+	select {
+	case w.port.ready <- true:
+	default:
+	}
+	return len(lcdData), nil
+}
+
+// ServePty serves sim on a new pty, which is symlinked as link in the user's home directory,
+// so that the app can open it in place of the controller board's serial device.
+func ServePty(sim *Sim, link string) (path string, err error) {
+	ptyFile, tty, err := pty.Open()
+	if err != nil {
+		return "", err
+	}
+	if err := pty.MakeRaw(ptyFile); err != nil {
+		return "", err
+	}
+
+	// Hold the tty open, so that the pty keeps working while the app isn't connected.
+	if _, err := os.OpenFile(tty, os.O_RDWR, 0); err != nil {
+		return "", err
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	path = homeDir + "/" + link
+	_ = os.Remove(path)
+	if err := os.Symlink(tty, path); err != nil {
+		return "", err
+	}
+
+	sim.out = ptyFile
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := ptyFile.Read(buf)
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			sim.Write(buf[:n])
+		}
+	}()
+	return path, nil
+}
@@ -0,0 +1,27 @@
+// Serve runs a simulated uSDX on a pty, for the app to connect to in place of the controller
+// board. E.g.:
+//
+//	go run ./usdxSim/serve -link ttyUSDXSIM
+//	go run . -usdx ~/ttyUSDXSIM
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"uSDX/usdxSim"
+)
+
+var link = flag.String("link", "ttyUSDXSIM", "Name of the pty's symlink in the home directory")
+
+func main() {
+	flag.Parse()
+	sim := usdxSim.New(ioutil.Discard)
+	path, err := usdxSim.ServePty(sim, *link)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sim.PowerOn()
+	log.Printf("Simulated uSDX on %s", path)
+	select {}
+}
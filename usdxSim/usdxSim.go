@@ -0,0 +1,437 @@
+// Package usdxSim simulates a uSDX with the controller board fitted, so the app can be developed
+// and tested without a radio. It takes the control bytes that controls/lowLevel.go sends, and
+// sends back the LCD writes that the firmware would make, in the controller board's format.
+//
+// Its screens are laid out as usdxScreen expects them, which hasn't been checked against a radio.
+// So checks against the simulator can't catch a layout that differs from the firmware's; that's
+// left to frames captured from a radio, in usdxScreen's tests.
+package usdxSim
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"uSDX/ambEmuLcd"
+	"uSDX/hd44780"
+)
+
+// Control bytes, as defined by the uSDX controller board.
+const (
+	clickLeftButton               = byte(1)
+	clickRightButton              = byte(2)
+	clickEncoderButton            = byte(3)
+	rotateEncoderClockwise        = byte(4)
+	rotateEncoderCounterclockwise = byte(5)
+	startPushToTalk               = byte(6)
+	endPushToTalk                 = byte(7)
+)
+
+// The screens the simulator can show.
+const (
+	splashScreen = iota
+	mainScreen
+	menuListScreen
+	menuEditScreen
+)
+
+// The splash screen, shown at power up. Its second line is the firmware version.
+const (
+	SplashLine1 = "uSDX"
+	SplashLine2 = "QCX-SSB R1.02w"
+
+	splashDuration = time.Second
+)
+
+const (
+	vfoAGlyph = 6
+	vfoBGlyph = 7
+
+	lcdCols = 16
+
+	// The frequency occupies columns 1 to 9 of the main screen's second line, as "dd,ddd,dd" in
+	// units of 10 Hz. The encoder button steps the cursor through them, commas included.
+	freqFirstCol = 1
+	freqChars    = 9
+	maxFrequency = 99999990
+)
+
+// The digit index (0 for 10 MHz to 6 for 10 Hz) shown in each of the frequency's columns, or -1.
+var freqColumnDigits = []int{-1, 0, 1, -1, 2, 3, 4, -1, 5, 6}
+
+// A Sim is a simulated uSDX and controller board. Its LCD writes go to out.
+type Sim struct {
+	mutex sync.Mutex
+	out   io.Writer
+
+	poweredOn    bool
+	screen       int
+	frequencyA   int64 // In Hz
+	frequencyB   int64
+	transmitting bool
+	sMeter       int // S units
+	cursorCol    int // On the main screen
+	menu         []setting
+	menuIndex    int
+	lastControl  time.Time
+}
+
+// New returns a Sim, powered off, whose LCD writes go to out.
+func New(out io.Writer) *Sim {
+	return &Sim{
+		out:        out,
+		frequencyA: 7074000,
+		frequencyB: 14074000,
+		sMeter:     5,
+		cursorCol:  6, // The 1 kHz digit
+		menu:       newMenu(),
+	}
+}
+
+// PowerOn switches the radio on, showing the splash screen and then the main screen.
+func (s *Sim) PowerOn() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var lcd lcdOutput
+	lcd.marker(powerUpMarker)
+	lcd.initSequence()
+	lcd.defineGlyphs()
+	s.poweredOn = true
+	s.screen = splashScreen
+	s.draw(&lcd)
+	s.send(&lcd)
+
+	time.AfterFunc(splashDuration, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.poweredOn && s.screen == splashScreen {
+			s.screen = mainScreen
+			s.redraw()
+		}
+	})
+}
+
+// PowerOff switches the radio off.
+func (s *Sim) PowerOff() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var lcd lcdOutput
+	lcd.marker(powerDownMarker)
+	s.poweredOn = false
+	s.send(&lcd)
+}
+
+// SetSMeter sets the signal strength, in S units, that the main screen shows.
+func (s *Sim) SetSMeter(sUnits int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sMeter = sUnits
+	s.redraw()
+}
+
+// Frequency returns the frequency of the VFO that's in use, in Hz.
+func (s *Sim) Frequency() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return *s.activeFrequency()
+}
+
+// Mode returns the mode's label, e.g. "USB".
+func (s *Sim) Mode() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return strings.TrimSpace(modeLabels[s.menu[modeSetting].value])
+}
+
+// Transmitting returns whether push to talk is on.
+func (s *Sim) Transmitting() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.transmitting
+}
+
+// Setting returns the displayed value of the named menu setting.
+func (s *Sim) Setting(name string) (value string, found bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.menu {
+		if s.menu[i].name == name {
+			return s.menu[i].display(), true
+		}
+	}
+	return "", false
+}
+
+// OnMainScreen returns whether the main screen is shown.
+func (s *Sim) OnMainScreen() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.poweredOn && s.screen == mainScreen
+}
+
+// Quiet returns whether no control bytes have been received for d, e.g. because the app has
+// finished setting the frequency.
+func (s *Sim) Quiet(d time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Since(s.lastControl) >= d
+}
+
+// Write takes control bytes from the app.
+func (s *Sim) Write(controls []byte) (int, error) {
+	for _, b := range controls {
+		s.control(b)
+	}
+	return len(controls), nil
+}
+
+func (s *Sim) control(b byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastControl = time.Now()
+	if !s.poweredOn || s.screen == splashScreen {
+		return
+	}
+
+	switch b {
+	case clickLeftButton:
+		switch s.screen {
+		case mainScreen:
+			s.screen = menuListScreen
+		case menuListScreen:
+			s.screen = menuEditScreen
+		case menuEditScreen:
+			s.screen = menuListScreen
+		}
+
+	case clickRightButton:
+		switch s.screen {
+		case mainScreen:
+			s.menu[modeSetting].value = (s.menu[modeSetting].value + 1) % len(modeLabels)
+		case menuListScreen:
+			s.screen = mainScreen
+		case menuEditScreen:
+			s.screen = menuListScreen
+		}
+
+	case clickEncoderButton:
+		if s.screen == mainScreen {
+			s.cursorCol = freqFirstCol + (s.cursorCol-freqFirstCol+1)%freqChars
+		}
+
+	case rotateEncoderClockwise, rotateEncoderCounterclockwise:
+		dir := 1
+		if b == rotateEncoderCounterclockwise {
+			dir = -1
+		}
+		switch s.screen {
+		case mainScreen:
+			s.tune(dir)
+		case menuListScreen:
+			s.menuIndex += dir
+			if s.menuIndex < 0 {
+				s.menuIndex = 0
+			} else if s.menuIndex >= len(s.menu) {
+				s.menuIndex = len(s.menu) - 1
+			}
+		case menuEditScreen:
+			s.menu[s.menuIndex].adjust(dir)
+		}
+
+	case startPushToTalk:
+		s.transmitting = true
+
+	case endPushToTalk:
+		s.transmitting = false
+
+	default:
+		log.Printf("Simulated uSDX: unknown control byte %d", b)
+		return
+	}
+	s.redraw()
+}
+
+// tune steps the digit under the cursor, carrying into the digits above it.
+func (s *Sim) tune(dir int) {
+	digit := freqColumnDigits[s.cursorCol]
+	if digit < 0 {
+		return // The cursor is on a comma.
+	}
+	step := int64(10)
+	for i := digit; i < 6; i++ {
+		step *= 10
+	}
+	f := s.activeFrequency()
+	*f += int64(dir) * step
+	if *f < 0 {
+		*f = 0
+	} else if *f > maxFrequency {
+		*f = maxFrequency
+	}
+}
+
+// activeFrequency returns the frequency of the VFO in use.
+func (s *Sim) activeFrequency() *int64 {
+	if s.displayedVfo() == vfoBGlyph {
+		return &s.frequencyB
+	}
+	return &s.frequencyA
+}
+
+func (s *Sim) displayedVfo() byte {
+	if s.menu[vfoModeSetting].value == vfoModeB {
+		return vfoBGlyph
+	}
+	return vfoAGlyph
+}
+
+func (s *Sim) redraw() {
+	if !s.poweredOn {
+		return
+	}
+	var lcd lcdOutput
+	s.draw(&lcd)
+	s.send(&lcd)
+}
+
+// draw writes the whole of the current screen, as the firmware does after every control.
+func (s *Sim) draw(lcd *lcdOutput) {
+	var line1, line2 string
+	cursorOn := false
+	cursorCol, cursorRow := 0, 0
+
+	switch s.screen {
+	case splashScreen:
+		line1, line2 = SplashLine1, SplashLine2
+
+	case mainScreen:
+		line1 = s.mainScreenLine1()
+		line2 = s.mainScreenLine2()
+		cursorOn, cursorCol, cursorRow = true, s.cursorCol, 1
+
+	case menuListScreen, menuEditScreen:
+		item := &s.menu[s.menuIndex]
+		line1 = item.id + " " + item.name
+		if s.screen == menuEditScreen {
+			line2 = ">" + item.display()
+			cursorOn, cursorCol, cursorRow = true, 1, 1
+		} else {
+			line2 = " " + item.display()
+		}
+	}
+
+	lcd.command(hd44780.CmdSetDdramAddr | 0x00)
+	lcd.text(pad(line1))
+	lcd.command(hd44780.CmdSetDdramAddr | 0x40)
+	lcd.text(pad(line2))
+	if cursorOn {
+		lcd.command(hd44780.CmdSetDdramAddr | byte(cursorRow*0x40+cursorCol))
+		lcd.command(hd44780.CmdDisplay | hd44780.CmdDisplayOn | hd44780.CmdDisplayCursor)
+	} else {
+		lcd.command(hd44780.CmdDisplay | hd44780.CmdDisplayOn)
+	}
+}
+
+func (s *Sim) mainScreenLine1() string {
+	line := make([]byte, lcdCols)
+	for i := range line {
+		line[i] = ' '
+	}
+	if s.transmitting {
+		copy(line, "TX")
+	}
+
+	// Four bar glyphs (2 to 5 for 0 to 3 bars) with one bar per S unit above S1.
+	bars := 0
+	if !s.transmitting && s.sMeter > 1 {
+		bars = s.sMeter - 1
+	}
+	for i := 0; i < 4; i++ {
+		n := bars - 3*i
+		if n < 0 {
+			n = 0
+		} else if n > 3 {
+			n = 3
+		}
+		line[12+i] = byte(2 + n)
+	}
+	return string(line)
+}
+
+func (s *Sim) mainScreenLine2() string {
+	daHz := fmt.Sprintf("%7d", *s.activeFrequency()/10)
+	freq := daHz[0:2] + "," + daHz[2:5] + "," + daHz[5:7]
+	return string([]byte{s.displayedVfo()}) + freq + " " + modeLabels[s.menu[modeSetting].value]
+}
+
+func pad(line string) string {
+	if len(line) > lcdCols {
+		return line[:lcdCols]
+	}
+	return line + strings.Repeat(" ", lcdCols-len(line))
+}
+
+func (s *Sim) send(lcd *lcdOutput) {
+	if _, err := s.out.Write(lcd.bytes); err != nil {
+		log.Printf("Simulated uSDX: %v", err)
+	}
+}
+
+// An lcdOutput collects LCD writes in the controller board's format: one byte per write, with
+// the RS bit and the 4 data lines.
+type lcdOutput struct {
+	bytes []byte
+}
+
+const (
+	powerUpMarker   = 0xFF
+	powerDownMarker = 0xFE
+)
+
+func (lcd *lcdOutput) write(rs byte, nibble byte) {
+	lcd.bytes = append(lcd.bytes, rs<<ambEmuLcd.RS_BIT|nibble&0x0F)
+}
+
+func (lcd *lcdOutput) writeByte(rs byte, b byte) {
+	lcd.write(rs, b>>4)
+	lcd.write(rs, b)
+}
+
+func (lcd *lcdOutput) command(b byte) {
+	lcd.writeByte(ambEmuLcd.CMD_REGISTER, b)
+}
+
+func (lcd *lcdOutput) text(s string) {
+	for i := 0; i < len(s); i++ {
+		lcd.writeByte(ambEmuLcd.DATA_REGISTER, s[i])
+	}
+}
+
+// marker writes one of the controller board's power markers, which aren't LCD instructions.
+func (lcd *lcdOutput) marker(b byte) {
+	lcd.writeByte(ambEmuLcd.CMD_REGISTER, b)
+}
+
+// initSequence switches the LCD to a 4 bit interface and sets it up, as the firmware does.
+func (lcd *lcdOutput) initSequence() {
+	for _, nibble := range []byte{0x3, 0x3, 0x3, 0x2} { // Over the 8 bit interface
+		lcd.write(ambEmuLcd.CMD_REGISTER, nibble)
+	}
+	lcd.command(hd44780.CmdFunction | hd44780.CmdFunction2Line)
+	lcd.command(hd44780.CmdDisplay | hd44780.CmdDisplayOn)
+	lcd.command(hd44780.CmdClear)
+	lcd.command(hd44780.CmdEntryMode | hd44780.CmdEntryModeIncrement)
+}
+
+func (lcd *lcdOutput) defineGlyphs() {
+	for i, glyph := range ambEmuLcd.UsdxGlyphs {
+		lcd.command(hd44780.CmdSetCgramAddr | byte(((i+1)&0x7)<<3))
+		for _, row := range glyph {
+			lcd.writeByte(ambEmuLcd.DATA_REGISTER, row)
+		}
+	}
+}